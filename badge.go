@@ -0,0 +1,78 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+)
+
+// badgeColors are the shields.io colors used for coverage badges.
+const (
+	badgeRed         = "#e05d44"
+	badgeYellow      = "#dfb317"
+	badgeGreen       = "#97ca00"
+	badgeBrightGreen = "#4c1"
+)
+
+// defaultBadgeThreshold is the coverage below which the badge is red when no
+// minimum coverage is configured.
+const defaultBadgeThreshold = 50.0
+
+const badgeSVG = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+  <title>%[4]s: %[5]s</title>
+  <linearGradient id="s" x2="0" y2="100%%">
+    <stop offset="0" stop-color="#bbb" stop-opacity=".1"/>
+    <stop offset="1" stop-opacity=".1"/>
+  </linearGradient>
+  <clipPath id="r">
+    <rect width="%[1]d" height="20" rx="3" fill="#fff"/>
+  </clipPath>
+  <g clip-path="url(#r)">
+    <rect width="%[2]d" height="20" fill="#555"/>
+    <rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/>
+    <rect width="%[1]d" height="20" fill="url(#s)"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+    <text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text>
+    <text x="%[7]d" y="14">%[4]s</text>
+    <text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text>
+    <text x="%[8]d" y="14">%[5]s</text>
+  </g>
+</svg>
+`
+
+// badgeColor returns the badge color for the coverage. Coverage below the
+// minimum coverage is red, and the range from the minimum coverage to 100% is
+// split evenly into yellow, green, and bright green bands.
+func badgeColor(coverage, mc float64) string {
+	if mc <= 0 {
+		mc = defaultBadgeThreshold
+	}
+	band := (100 - mc) / 3
+	switch {
+	case coverage < mc:
+		return badgeRed
+	case coverage < mc+band:
+		return badgeYellow
+	case coverage < mc+2*band:
+		return badgeGreen
+	}
+	return badgeBrightGreen
+}
+
+// badgeTextWidth approximates the width in pixels of text rendered in the
+// badge font.
+func badgeTextWidth(s string) int {
+	return 7*len(s) + 10
+}
+
+// coverageBadge returns a shields.io style SVG badge for the coverage.
+func coverageBadge(coverage, mc float64) string {
+	label, value := "coverage", percentFormat(coverage)
+	labelWidth, valueWidth := badgeTextWidth(label), badgeTextWidth(value)
+	return fmt.Sprintf(badgeSVG, labelWidth+valueWidth, labelWidth, valueWidth, label, value, badgeColor(coverage, mc), labelWidth/2, labelWidth+valueWidth/2)
+}
+
+// writeCoverageBadge writes a coverage badge to the provided file.
+func writeCoverageBadge(filename string, coverage, mc float64) error {
+	return os.WriteFile(filename, []byte(coverageBadge(coverage, mc)), 0644)
+}
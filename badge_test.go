@@ -0,0 +1,53 @@
+package gocli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBadgeColor(t *testing.T) {
+	for _, test := range []struct {
+		coverage float64
+		mc       float64
+		want     string
+	}{
+		{coverage: 49.9, want: badgeRed},
+		{coverage: 50, want: badgeYellow},
+		{coverage: 66.6, want: badgeYellow},
+		{coverage: 66.7, want: badgeGreen},
+		{coverage: 83.4, want: badgeBrightGreen},
+		{coverage: 100, want: badgeBrightGreen},
+		{coverage: 79.9, mc: 80, want: badgeRed},
+		{coverage: 80, mc: 80, want: badgeYellow},
+		{coverage: 90, mc: 80, want: badgeGreen},
+		{coverage: 95, mc: 80, want: badgeBrightGreen},
+	} {
+		if got := badgeColor(test.coverage, test.mc); got != test.want {
+			t.Errorf("badgeColor(%v, %v) returned %q; want %q", test.coverage, test.mc, got, test.want)
+		}
+	}
+}
+
+func TestWriteCoverageBadge(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "coverage.svg")
+	if err := writeCoverageBadge(filename, 87.25, 80); err != nil {
+		t.Fatalf("writeCoverageBadge() returned error: %v", err)
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read badge: %v", err)
+	}
+
+	for _, want := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="111" height="20" role="img" aria-label="coverage: 87.2%">`,
+		`<rect x="66" width="45" height="20" fill="#97ca00"/>`,
+		`<text x="33" y="14">coverage</text>`,
+		`<text x="88" y="14">87.2%</text>`,
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("badge doesn't contain %q:\n%s", want, b)
+		}
+	}
+}
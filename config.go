@@ -0,0 +1,217 @@
+package gocli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leep-frog/command/command"
+)
+
+const configFileName = ".gocli.json"
+
+// gtConfig contains project defaults for the `gt` command. Every field is
+// optional and is only used if the corresponding flag (or argument) isn't
+// provided on the command line.
+type gtConfig struct {
+	Paths            []string `json:"paths"`
+	MinCoverage      *float64 `json:"minCoverage"`
+	Timeout          *int     `json:"timeout"`
+	PackageCount     *int     `json:"packageCount"`
+	PackageCoverage  []string `json:"packageCoverage"`
+	Exclude          []string `json:"exclude"`
+	RequireExported  bool     `json:"requireExported"`
+	RegressTolerance *float64 `json:"regressTolerance"`
+	JUnit            string   `json:"junit"`
+	JSONOut          string   `json:"jsonOut"`
+	Badge            string   `json:"badge"`
+}
+
+// findConfigFile walks up from the provided directory to the module root and
+// returns the path to the closest config file (or an empty string if there
+// isn't one). If there is no module, only the provided directory is checked.
+func findConfigFile(dir string, mod *goModule) (string, error) {
+	for {
+		filename := filepath.Join(dir, configFileName)
+		if _, err := os.Stat(filename); err == nil {
+			return filename, nil
+		} else if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to check for config file: %v", err)
+		}
+
+		parent := filepath.Dir(dir)
+		if mod == nil || dir == mod.Dir || parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// readConfig reads and validates the provided config file.
+func readConfig(filename string) (*gtConfig, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	cfg := &gtConfig{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", filename, err)
+	}
+
+	if cfg.MinCoverage != nil && (*cfg.MinCoverage < 0 || *cfg.MinCoverage > 100) {
+		return nil, fmt.Errorf("invalid config file %s: minCoverage must be between 0 and 100", filename)
+	}
+	if cfg.Timeout != nil && *cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid config file %s: timeout must be positive", filename)
+	}
+	if cfg.RegressTolerance != nil && (*cfg.RegressTolerance < 0 || *cfg.RegressTolerance > 100) {
+		return nil, fmt.Errorf("invalid config file %s: regressTolerance must be between 0 and 100", filename)
+	}
+	if cfg.PackageCount != nil && *cfg.PackageCount < 0 {
+		return nil, fmt.Errorf("invalid config file %s: packageCount must be non-negative", filename)
+	}
+	return cfg, nil
+}
+
+// loadConfig returns the config for the current directory (or an empty
+// config if there isn't a config file).
+func loadConfig() (*gtConfig, error) {
+	wd, err := getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current directory: %v", err)
+	}
+	mod, err := findGoModule(wd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find go module: %v", err)
+	}
+	filename, err := findConfigFile(wd, mod)
+	if err != nil || filename == "" {
+		return &gtConfig{}, err
+	}
+	cfg, err := readConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Relative paths in the config file are relative to the config file's
+	// directory, but paths on the command line are relative to the current
+	// directory.
+	dir := filepath.Dir(filename)
+	for i, p := range cfg.Paths {
+		cfg.Paths[i] = rebasePath(p, dir, wd)
+	}
+	for i, spec := range cfg.PackageCoverage {
+		if pattern, percent, ok := strings.Cut(spec, "="); ok {
+			cfg.PackageCoverage[i] = fmt.Sprintf("%s=%s", rebasePath(pattern, dir, wd), percent)
+		}
+	}
+	return cfg, nil
+}
+
+// isRelativePattern returns whether the path or package pattern is relative
+// to the current directory (i.e. starts with `.` or `..`).
+func isRelativePattern(p string) bool {
+	return p == "." || p == ".." || strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../")
+}
+
+// rebasePath returns the relative path (or package pattern) from directory
+// `from` as a path relative to directory `to`. Other paths (e.g. import paths)
+// are returned as is.
+func rebasePath(p, from, to string) string {
+	if !isRelativePattern(p) {
+		return p
+	}
+	rel, err := filepath.Rel(to, filepath.Join(from, filepath.FromSlash(p)))
+	if err != nil {
+		return p
+	}
+	rel = filepath.ToSlash(rel)
+	// The go command requires relative patterns to start with a dot.
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return rel
+	}
+	return "./" + rel
+}
+
+// setDefault sets the value in data if it wasn't provided on the command line.
+func setDefault[T any](d *command.Data, name string, v T) {
+	if !d.Has(name) {
+		d.Set(name, v)
+	}
+}
+
+// applyConfig populates data with values from the config file (and the
+// built-in defaults) for any flags and arguments that weren't provided.
+func applyConfig(i *command.Input, d *command.Data) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.Paths) > 0 {
+		setDefault(d, pathArgs.Name(), cfg.Paths)
+	}
+	setDefault(d, pathArgs.Name(), []string{"."})
+	// Coverage isn't checked when filtering or rerunning tests, so the config's
+	// thresholds only apply to full runs.
+	if !d.Has(funcFilterFlag.Name()) && !failedFlag.Get(d) {
+		applyCoverageConfig(cfg, d)
+	}
+	setDefault(d, minCoverageFlag.Name(), 0.0)
+	if cfg.Timeout != nil {
+		setDefault(d, timeoutFlag.Name(), *cfg.Timeout)
+	}
+	if cfg.PackageCount != nil && !failedFlag.Get(d) {
+		setDefault(d, packageCountFlag.Name(), *cfg.PackageCount)
+	}
+	if cfg.RegressTolerance != nil {
+		setDefault(d, regressTolFlag.Name(), *cfg.RegressTolerance)
+	}
+	if cfg.JUnit != "" {
+		setDefault(d, junitFlag.Name(), cfg.JUnit)
+	}
+	if cfg.JSONOut != "" {
+		setDefault(d, jsonOutFlag.Name(), cfg.JSONOut)
+	}
+	if cfg.Badge != "" {
+		setDefault(d, badgeFlag.Name(), cfg.Badge)
+	}
+	return nil
+}
+
+// applyMergeConfig populates data with the coverage thresholds from the config
+// file for the merge command.
+func applyMergeConfig(i *command.Input, d *command.Data) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	applyCoverageConfig(cfg, d)
+	setDefault(d, minCoverageFlag.Name(), 0.0)
+	return nil
+}
+
+// applyCoverageConfig populates data with the coverage thresholds from the
+// config file (shared by the test and merge commands).
+func applyCoverageConfig(cfg *gtConfig, d *command.Data) {
+	if cfg.MinCoverage != nil {
+		setDefault(d, minCoverageFlag.Name(), *cfg.MinCoverage)
+	}
+	// Diff coverage replaces the per-package thresholds, so the config's rules
+	// aren't used with the diff-base flag.
+	if len(cfg.PackageCoverage) > 0 && !diffBaseFlag.Provided(d) {
+		setDefault(d, coverageRuleFlag.Name(), cfg.PackageCoverage)
+	}
+	if len(cfg.Exclude) > 0 {
+		setDefault(d, excludeFlag.Name(), cfg.Exclude)
+	}
+	if cfg.RequireExported && !noRequireExpFlag.Get(d) {
+		setDefault(d, requireExpFlag.Name(), true)
+	}
+}
@@ -0,0 +1,241 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/leep-frog/command/command"
+	"github.com/leep-frog/command/commandertest"
+	"github.com/leep-frog/command/commandtest"
+)
+
+func writeFile(t *testing.T, filename, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}
+
+func TestFindConfigFile(t *testing.T) {
+	root := t.TempDir()
+	modDir := filepath.Join(root, "mod")
+	writeFile(t, filepath.Join(root, configFileName), "{}")
+	writeFile(t, filepath.Join(modDir, "go.mod"), "module example.com/mod\n")
+	writeFile(t, filepath.Join(modDir, "a", configFileName), "{}")
+	mod := &goModule{"example.com/mod", modDir}
+
+	for _, test := range []struct {
+		name string
+		dir  string
+		mod  *goModule
+		want string
+	}{
+		{
+			name: "finds config in directory",
+			dir:  filepath.Join(modDir, "a"),
+			mod:  mod,
+			want: filepath.Join(modDir, "a", configFileName),
+		},
+		{
+			name: "finds config in parent directory",
+			dir:  filepath.Join(modDir, "a", "b", "c"),
+			mod:  mod,
+			want: filepath.Join(modDir, "a", configFileName),
+		},
+		{
+			name: "doesn't search above module root",
+			dir:  filepath.Join(modDir, "other"),
+			mod:  mod,
+		},
+		{
+			name: "only checks directory if no module",
+			dir:  filepath.Join(root, "other"),
+		},
+		{
+			name: "finds config in directory if no module",
+			dir:  root,
+			want: filepath.Join(root, configFileName),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := findConfigFile(test.dir, test.mod)
+			if err != nil {
+				t.Fatalf("findConfigFile(%q) returned error: %v", test.dir, err)
+			}
+			if got != test.want {
+				t.Errorf("findConfigFile(%q) returned %q; want %q", test.dir, got, test.want)
+			}
+		})
+	}
+}
+
+func TestReadConfig(t *testing.T) {
+	mc := 75.5
+	timeout := 120
+	for _, test := range []struct {
+		name     string
+		contents string
+		want     *gtConfig
+		wantErr  string
+	}{
+		{
+			name:     "reads config",
+			contents: `{"paths": ["./..."], "minCoverage": 75.5, "timeout": 120, "packageCoverage": ["./cmd/...=0"], "junit": "junit.xml"}`,
+			want: &gtConfig{
+				Paths:           []string{"./..."},
+				MinCoverage:     &mc,
+				Timeout:         &timeout,
+				PackageCoverage: []string{"./cmd/...=0"},
+				JUnit:           "junit.xml",
+			},
+		},
+		{
+			name:     "fails if unknown field",
+			contents: `{"minCoverag": 75}`,
+			wantErr:  `failed to parse config file %s: json: unknown field "minCoverag"`,
+		},
+		{
+			name:     "fails if invalid min coverage",
+			contents: `{"minCoverage": 101}`,
+			wantErr:  "invalid config file %s: minCoverage must be between 0 and 100",
+		},
+		{
+			name:     "fails if invalid timeout",
+			contents: `{"timeout": 0}`,
+			wantErr:  "invalid config file %s: timeout must be positive",
+		},
+		{
+			name:     "fails if invalid package count",
+			contents: `{"packageCount": -1}`,
+			wantErr:  "invalid config file %s: packageCount must be non-negative",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), configFileName)
+			writeFile(t, filename, test.contents)
+
+			var wantErr error
+			if test.wantErr != "" {
+				wantErr = fmt.Errorf(test.wantErr, filename)
+			}
+			got, err := readConfig(filename)
+			if diff := cmp.Diff(wantErr, err, cmpErrors()); diff != "" {
+				t.Errorf("readConfig() returned incorrect error (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("readConfig() returned incorrect config (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	modDir := t.TempDir()
+	writeFile(t, filepath.Join(modDir, "go.mod"), "module example.com/mod\n")
+	writeFile(t, filepath.Join(modDir, configFileName), `{"paths": ["./...", "example.com/mod/b"], "minCoverage": 75, "timeout": 120, "packageCount": 1, "packageCoverage": ["./a=50", "./sub/...=60"], "requireExported": true}`)
+	commandtest.StubValue(t, &getwd, func() (string, error) {
+		return filepath.Join(modDir, "sub"), nil
+	})
+	coverProfile := filepath.Join(t.TempDir(), "cover.out")
+	commandtest.StubValue(t, &tmpFile, func() (*os.File, error) {
+		return os.Create(coverProfile)
+	})
+
+	for _, test := range []struct {
+		name     string
+		args     []string
+		wantArgs []string
+		wantData map[string]interface{}
+	}{
+		{
+			name:     "uses config values",
+			wantArgs: []string{"test", "-json", "-timeout", "120s", "../...", "example.com/mod/b", fmt.Sprintf("-coverprofile=%s", coverProfile)},
+			wantData: map[string]interface{}{
+				pathArgs.Name():         []string{"../...", "example.com/mod/b"},
+				minCoverageFlag.Name():  75.0,
+				timeoutFlag.Name():      120,
+				packageCountFlag.Name(): 1,
+				coverageRuleFlag.Name(): []string{"../a=50", "./...=60"},
+				requireExpFlag.Name():   true,
+			},
+		},
+		{
+			name:     "flags override config values",
+			args:     []string{"p1", "-t", "5", "-m", "50"},
+			wantArgs: []string{"test", "-json", "-timeout", "5s", "p1", fmt.Sprintf("-coverprofile=%s", coverProfile)},
+			wantData: map[string]interface{}{
+				pathArgs.Name():         []string{"p1"},
+				minCoverageFlag.Name():  50.0,
+				timeoutFlag.Name():      5,
+				packageCountFlag.Name(): 1,
+				coverageRuleFlag.Name(): []string{"../a=50", "./...=60"},
+				requireExpFlag.Name():   true,
+			},
+		},
+		{
+			name:     "flags negate config values",
+			args:     []string{"--no-require-exported"},
+			wantArgs: []string{"test", "-json", "-timeout", "120s", "../...", "example.com/mod/b", fmt.Sprintf("-coverprofile=%s", coverProfile)},
+			wantData: map[string]interface{}{
+				pathArgs.Name():         []string{"../...", "example.com/mod/b"},
+				minCoverageFlag.Name():  75.0,
+				timeoutFlag.Name():      120,
+				packageCountFlag.Name(): 1,
+				coverageRuleFlag.Name(): []string{"../a=50", "./...=60"},
+				noRequireExpFlag.Name(): true,
+			},
+		},
+		{
+			name:     "doesn't use config coverage thresholds with func-filter flag",
+			args:     []string{"-f", "Add"},
+			wantArgs: []string{"test", "-json", "-timeout", "120s", "../...", "example.com/mod/b", "-run", "(Add)"},
+			wantData: map[string]interface{}{
+				pathArgs.Name():         []string{"../...", "example.com/mod/b"},
+				funcFilterFlag.Name():   []string{"Add"},
+				minCoverageFlag.Name():  0.0,
+				timeoutFlag.Name():      120,
+				packageCountFlag.Name(): 1,
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			test.wantData["COVERAGE"] = map[string]*packageResult{
+				"p1": {
+					TestResult: noTestFiles,
+					Line:       noTestLine("p1"),
+				},
+			}
+			commandertest.ExecuteTest(t, &commandtest.ExecuteTestCase{
+				Node:         CLI().Node(),
+				Args:         test.args,
+				RunResponses: []*commandtest.FakeRun{{Stdout: noTestEvents("p1")}},
+				WantStdout:   stdoutLines(noTestLine("p1")),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: test.wantArgs,
+				}},
+				WantData: &command.Data{Values: test.wantData},
+			})
+		})
+	}
+
+	t.Run("doesn't use config thresholds with failed flag", func(t *testing.T) {
+		commandertest.ExecuteTest(t, &commandtest.ExecuteTestCase{
+			Node:       CLI().Node(),
+			Args:       []string{"--failed"},
+			WantStdout: "No failed tests from the previous run\n",
+			WantData: &command.Data{Values: map[string]interface{}{
+				pathArgs.Name():        []string{"../...", "example.com/mod/b"},
+				minCoverageFlag.Name(): 0.0,
+				timeoutFlag.Name():     120,
+				failedFlag.Name():      true,
+			}},
+		})
+	})
+}
@@ -0,0 +1,318 @@
+package gocli
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+var (
+	coverModeRegex  = regexp.MustCompile(`^mode: (set|count|atomic)$`)
+	coverBlockRegex = regexp.MustCompile(`^(.+):([0-9]+)\.([0-9]+),([0-9]+)\.([0-9]+) ([0-9]+) ([0-9]+)$`)
+)
+
+// coverBlock is a single block of code from a coverage profile.
+type coverBlock struct {
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	NumStmt   int
+	Count     int
+}
+
+func (cb *coverBlock) key() [4]int {
+	return [4]int{cb.StartLine, cb.StartCol, cb.EndLine, cb.EndCol}
+}
+
+// coverProfile is the parsed contents of a `go test -coverprofile` file.
+type coverProfile struct {
+	// Mode is the cover mode (set, count, or atomic).
+	Mode string
+	// Files is a map from file name (e.g. `github.com/user/module/pkg/file.go`)
+	// to the blocks in that file, sorted by position.
+	Files map[string][]*coverBlock
+}
+
+// readCoverProfile reads and parses the provided coverage profile file.
+func readCoverProfile(filename string) (*coverProfile, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open coverage profile: %v", err)
+	}
+	defer f.Close()
+	return parseCoverProfile(f)
+}
+
+// parseCoverProfile parses the contents of a coverage profile. Blocks that
+// appear multiple times (which happens when multiple test binaries cover the
+// same package) are merged.
+func parseCoverProfile(r io.Reader) (*coverProfile, error) {
+	cp := &coverProfile{
+		Files: map[string][]*coverBlock{},
+	}
+	blocks := map[string]map[[4]int]*coverBlock{}
+	lineNum := 0
+	for scanner := bufio.NewScanner(r); scanner.Scan(); {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if m := coverModeRegex.FindStringSubmatch(line); m != nil {
+			if cp.Mode != "" && cp.Mode != m[1] {
+				return nil, fmt.Errorf("inconsistent cover modes in coverage profile: %q and %q", cp.Mode, m[1])
+			}
+			cp.Mode = m[1]
+			continue
+		}
+
+		if cp.Mode == "" {
+			return nil, fmt.Errorf("coverage profile line %d: expected mode line, got %q", lineNum, line)
+		}
+
+		m := coverBlockRegex.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("coverage profile line %d: invalid block %q", lineNum, line)
+		}
+		var nums []int
+		for _, s := range m[2:] {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, fmt.Errorf("coverage profile line %d: invalid number %q: %v", lineNum, s, err)
+			}
+			nums = append(nums, n)
+		}
+		cb := &coverBlock{nums[0], nums[1], nums[2], nums[3], nums[4], nums[5]}
+
+		fileBlocks, ok := blocks[m[1]]
+		if !ok {
+			fileBlocks = map[[4]int]*coverBlock{}
+			blocks[m[1]] = fileBlocks
+		}
+		if prev, ok := fileBlocks[cb.key()]; ok {
+			if prev.NumStmt != cb.NumStmt {
+				return nil, fmt.Errorf("coverage profile line %d: inconsistent statement count for block in %s", lineNum, m[1])
+			}
+			prev.Count = mergeCounts(cp.Mode, prev.Count, cb.Count)
+			continue
+		}
+		fileBlocks[cb.key()] = cb
+		cp.Files[m[1]] = append(cp.Files[m[1]], cb)
+	}
+
+	for _, fileBlocks := range cp.Files {
+		sortBlocks(fileBlocks)
+	}
+	return cp, nil
+}
+
+// mergeCounts merges the counts of the same block in the provided cover mode.
+func mergeCounts(mode string, a, b int) int {
+	if mode == "set" {
+		if a > 0 || b > 0 {
+			return 1
+		}
+		return 0
+	}
+	return a + b
+}
+
+func sortBlocks(blocks []*coverBlock) {
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].StartLine != blocks[j].StartLine {
+			return blocks[i].StartLine < blocks[j].StartLine
+		}
+		return blocks[i].StartCol < blocks[j].StartCol
+	})
+}
+
+// coverageCounts contains the number of total and covered statements.
+type coverageCounts struct {
+	Statements int
+	Covered    int
+}
+
+func (cc *coverageCounts) add(blocks ...*coverBlock) {
+	for _, b := range blocks {
+		cc.Statements += b.NumStmt
+		if b.Count > 0 {
+			cc.Covered += b.NumStmt
+		}
+	}
+}
+
+// Percent returns the percentage of statements that are covered.
+func (cc *coverageCounts) Percent() float64 {
+	if cc.Statements == 0 {
+		return 0
+	}
+	return 100 * float64(cc.Covered) / float64(cc.Statements)
+}
+
+// coverageReport contains the coverage computed from a coverage profile.
+type coverageReport struct {
+	// Counts is the coverage across all packages.
+	Counts coverageCounts
+	// Packages is a map from package import path to package coverage.
+	Packages map[string]*packageCoverage
+	// Excluded is the number of statements that were excluded from coverage.
+	Excluded int
+}
+
+// packageCoverage contains the coverage of a single package.
+type packageCoverage struct {
+	Name   string
+	Counts coverageCounts
+	Files  []*fileCoverage
+}
+
+// fileCoverage contains the coverage of a single file.
+type fileCoverage struct {
+	// Name is the file name as it appears in the coverage profile.
+	Name string
+	// Path is the local path to the file (or empty if it could not be found).
+	Path   string
+	Counts coverageCounts
+	Blocks []*coverBlock
+	Funcs  []*funcCoverage
+}
+
+// funcCoverage contains the coverage of a single function.
+type funcCoverage struct {
+	// Name is the function name (including the receiver for methods).
+	Name      string
+	Exported  bool
+	StartLine int
+	EndLine   int
+	Counts    coverageCounts
+}
+
+// packageCoverage returns the coverage for the provided package (or nil if
+// there is no coverage data for the package).
+func (cr *coverageReport) packageCoverage(pkg string) *packageCoverage {
+	if cr == nil {
+		return nil
+	}
+	return cr.Packages[pkg]
+}
+
+// computeCoverage computes per-package, per-file, and per-function coverage
+// from the provided profile. Function coverage is only computed for files
+// that can be found in the provided module.
+func computeCoverage(cp *coverProfile, mod *goModule) *coverageReport {
+	cr := &coverageReport{
+		Packages: map[string]*packageCoverage{},
+	}
+
+	names := maps.Keys(cp.Files)
+	slices.Sort(names)
+	for _, name := range names {
+		fc := &fileCoverage{
+			Name:   name,
+			Path:   mod.sourcePath(name),
+			Blocks: cp.Files[name],
+		}
+		fc.Counts.add(fc.Blocks...)
+		fc.Funcs = computeFuncCoverage(fc.Path, fc.Blocks)
+
+		pkg := path.Dir(name)
+		pc, ok := cr.Packages[pkg]
+		if !ok {
+			pc = &packageCoverage{Name: pkg}
+			cr.Packages[pkg] = pc
+		}
+		pc.Files = append(pc.Files, fc)
+		pc.Counts.add(fc.Blocks...)
+		cr.Counts.add(fc.Blocks...)
+	}
+	return cr
+}
+
+// computeFuncCoverage maps the blocks of a file to the function declarations
+// that contain them. Files that can't be parsed are ignored.
+func computeFuncCoverage(filename string, blocks []*coverBlock) []*funcCoverage {
+	if filename == "" {
+		return nil
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		return nil
+	}
+
+	var funcs []*funcCoverage
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Body == nil {
+			continue
+		}
+		start, end := fset.Position(fd.Pos()), fset.Position(fd.End())
+		fnc := &funcCoverage{
+			Name:      funcName(fd),
+			Exported:  isExportedFunc(fd),
+			StartLine: start.Line,
+			EndLine:   end.Line,
+		}
+		for _, b := range blocks {
+			if positionBefore(b.StartLine, b.StartCol, start.Line, start.Column) || positionBefore(end.Line, end.Column, b.StartLine, b.StartCol) {
+				continue
+			}
+			fnc.Counts.add(b)
+		}
+		funcs = append(funcs, fnc)
+	}
+	return funcs
+}
+
+func positionBefore(line, col, otherLine, otherCol int) bool {
+	return line < otherLine || (line == otherLine && col < otherCol)
+}
+
+// receiverType returns the type name of a method receiver (e.g. `*goCLI`).
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return "?"
+}
+
+func funcName(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return fd.Name.Name
+	}
+	return fmt.Sprintf("(%s).%s", receiverType(fd.Recv.List[0].Type), fd.Name.Name)
+}
+
+// isExportedFunc returns whether or not the function is exported (and, for
+// methods, whether the receiver type is also exported).
+func isExportedFunc(fd *ast.FuncDecl) bool {
+	if !fd.Name.IsExported() {
+		return false
+	}
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return true
+	}
+	return ast.IsExported(strings.TrimPrefix(receiverType(fd.Recv.List[0].Type), "*"))
+}
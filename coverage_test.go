@@ -0,0 +1,252 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	testModulePath = "github.com/leep-frog/gocli"
+	testCoverFile  = testModulePath + "/testdata/cover/cover.go"
+)
+
+func testModule(t *testing.T) *goModule {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	return &goModule{testModulePath, wd}
+}
+
+func TestParseCoverProfile(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		profile []string
+		want    *coverProfile
+		wantErr error
+	}{
+		{
+			name: "handles empty profile",
+			want: &coverProfile{
+				Files: map[string][]*coverBlock{},
+			},
+		},
+		{
+			name: "parses blocks",
+			profile: []string{
+				"mode: count",
+				"p1/b.go:3.4,5.6 2 0",
+				"p1/a.go:7.1,8.2 1 3",
+				"p1/a.go:1.1,2.2 4 1",
+			},
+			want: &coverProfile{
+				Mode: "count",
+				Files: map[string][]*coverBlock{
+					"p1/a.go": {
+						{1, 1, 2, 2, 4, 1},
+						{7, 1, 8, 2, 1, 3},
+					},
+					"p1/b.go": {
+						{3, 4, 5, 6, 2, 0},
+					},
+				},
+			},
+		},
+		{
+			name: "sums duplicate blocks in count mode",
+			profile: []string{
+				"mode: count",
+				"p1/a.go:1.1,2.2 4 1",
+				"p1/a.go:1.1,2.2 4 2",
+			},
+			want: &coverProfile{
+				Mode: "count",
+				Files: map[string][]*coverBlock{
+					"p1/a.go": {
+						{1, 1, 2, 2, 4, 3},
+					},
+				},
+			},
+		},
+		{
+			name: "ors duplicate blocks in set mode",
+			profile: []string{
+				"mode: set",
+				"p1/a.go:1.1,2.2 4 0",
+				"p1/a.go:1.1,2.2 4 1",
+				"p1/a.go:1.1,2.2 4 1",
+			},
+			want: &coverProfile{
+				Mode: "set",
+				Files: map[string][]*coverBlock{
+					"p1/a.go": {
+						{1, 1, 2, 2, 4, 1},
+					},
+				},
+			},
+		},
+		{
+			name: "allows repeated mode lines",
+			profile: []string{
+				"mode: set",
+				"p1/a.go:1.1,2.2 4 0",
+				"mode: set",
+				"p2/a.go:1.1,2.2 4 1",
+			},
+			want: &coverProfile{
+				Mode: "set",
+				Files: map[string][]*coverBlock{
+					"p1/a.go": {
+						{1, 1, 2, 2, 4, 0},
+					},
+					"p2/a.go": {
+						{1, 1, 2, 2, 4, 1},
+					},
+				},
+			},
+		},
+		{
+			name: "fails if inconsistent modes",
+			profile: []string{
+				"mode: set",
+				"mode: count",
+			},
+			wantErr: fmt.Errorf(`inconsistent cover modes in coverage profile: "set" and "count"`),
+		},
+		{
+			name: "fails if no mode line",
+			profile: []string{
+				"p1/a.go:1.1,2.2 4 0",
+			},
+			wantErr: fmt.Errorf(`coverage profile line 1: expected mode line, got "p1/a.go:1.1,2.2 4 0"`),
+		},
+		{
+			name: "fails if invalid block",
+			profile: []string{
+				"mode: set",
+				"p1/a.go:1.1,2.2 four 0",
+			},
+			wantErr: fmt.Errorf(`coverage profile line 2: invalid block "p1/a.go:1.1,2.2 four 0"`),
+		},
+		{
+			name: "fails if inconsistent statement counts",
+			profile: []string{
+				"mode: set",
+				"p1/a.go:1.1,2.2 4 0",
+				"p1/a.go:1.1,2.2 3 0",
+			},
+			wantErr: fmt.Errorf(`coverage profile line 3: inconsistent statement count for block in p1/a.go`),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseCoverProfile(strings.NewReader(strings.Join(test.profile, "\n")))
+			if diff := cmp.Diff(test.wantErr, err, cmpErrors()); diff != "" {
+				t.Errorf("parseCoverProfile() returned incorrect error (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("parseCoverProfile() returned incorrect profile (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestComputeCoverage(t *testing.T) {
+	mod := testModule(t)
+	coverBlocks := []*coverBlock{
+		{3, 26, 4, 7, 1, 1},
+		{4, 7, 6, 3, 1, 1},
+		{7, 2, 7, 10, 1, 0},
+		{12, 26, 14, 2, 1, 0},
+	}
+	otherBlocks := []*coverBlock{
+		{1, 1, 2, 2, 3, 2},
+	}
+	cp := &coverProfile{
+		Mode: "count",
+		Files: map[string][]*coverBlock{
+			testCoverFile:   coverBlocks,
+			"p1/other.go":   otherBlocks,
+			"p1/missing.go": nil,
+		},
+	}
+
+	want := &coverageReport{
+		Counts: coverageCounts{7, 5},
+		Packages: map[string]*packageCoverage{
+			testModulePath + "/testdata/cover": {
+				Counts: coverageCounts{4, 2},
+				Name:   testModulePath + "/testdata/cover",
+				Files: []*fileCoverage{{
+					Counts: coverageCounts{4, 2},
+					Name:   testCoverFile,
+					Path:   filepath.Join(mod.Dir, "testdata", "cover", "cover.go"),
+					Blocks: coverBlocks,
+					Funcs: []*funcCoverage{
+						{
+							Counts:    coverageCounts{3, 2},
+							Name:      "Covered",
+							Exported:  true,
+							StartLine: 3,
+							EndLine:   8,
+						},
+						{
+							Counts:    coverageCounts{1, 0},
+							Name:      "(*thing).method",
+							StartLine: 12,
+							EndLine:   14,
+						},
+					},
+				}},
+			},
+			"p1": {
+				Counts: coverageCounts{3, 3},
+				Name:   "p1",
+				Files: []*fileCoverage{
+					{
+						Name: "p1/missing.go",
+					},
+					{
+						Counts: coverageCounts{3, 3},
+						Name:   "p1/other.go",
+						Blocks: otherBlocks,
+					},
+				},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(want, computeCoverage(cp, mod)); diff != "" {
+		t.Errorf("computeCoverage() returned incorrect report (-want, +got):\n%s", diff)
+	}
+}
+
+func TestCoveragePercent(t *testing.T) {
+	for _, test := range []struct {
+		cc   coverageCounts
+		want float64
+	}{
+		{coverageCounts{0, 0}, 0},
+		{coverageCounts{4, 0}, 0},
+		{coverageCounts{4, 1}, 25},
+		{coverageCounts{4, 4}, 100},
+	} {
+		if got := test.cc.Percent(); got != test.want {
+			t.Errorf("%v.Percent() returned %v; want %v", test.cc, got, test.want)
+		}
+	}
+}
+
+func cmpErrors() cmp.Option {
+	return cmp.Comparer(func(this, that error) bool {
+		if this == nil || that == nil {
+			return this == nil && that == nil
+		}
+		return this.Error() == that.Error()
+	})
+}
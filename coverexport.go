@@ -0,0 +1,243 @@
+package gocli
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+var (
+	// timeNow is a variable so it can be stubbed in tests.
+	timeNow = time.Now
+)
+
+// lineHits returns a map from line number to the number of times the line
+// was run. Lines that are part of multiple blocks use the largest count.
+func lineHits(blocks []*coverBlock) map[int]int {
+	hits := map[int]int{}
+	for _, b := range blocks {
+		if b.NumStmt == 0 {
+			continue
+		}
+		for line := b.StartLine; line <= b.EndLine; line++ {
+			if count, ok := hits[line]; !ok || b.Count > count {
+				hits[line] = b.Count
+			}
+		}
+	}
+	return hits
+}
+
+// sortedLines returns the line numbers in hits in ascending order.
+func sortedLines(hits map[int]int) []int {
+	lines := maps.Keys(hits)
+	slices.Sort(lines)
+	return lines
+}
+
+// funcHits returns the number of times a function was run (i.e. the largest
+// count of any of the blocks in the function).
+func funcHits(f *funcCoverage, hits map[int]int) int {
+	var r int
+	for line := f.StartLine; line <= f.EndLine; line++ {
+		r = max(r, hits[line])
+	}
+	return r
+}
+
+// sortedFiles returns every file in the report, sorted by package and name.
+func sortedFiles(report *coverageReport) []*fileCoverage {
+	var r []*fileCoverage
+	pkgs := maps.Keys(report.Packages)
+	slices.Sort(pkgs)
+	for _, pkg := range pkgs {
+		r = append(r, report.Packages[pkg].Files...)
+	}
+	return r
+}
+
+// lcovReport returns the coverage report in LCOV format.
+func lcovReport(report *coverageReport, mod *goModule) []byte {
+	var buf bytes.Buffer
+	for _, fc := range sortedFiles(report) {
+		hits := lineHits(fc.Blocks)
+		buf.WriteString("TN:\n")
+		fmt.Fprintf(&buf, "SF:%s\n", mod.relativePath(fc.Name))
+
+		var funcsHit int
+		for _, f := range fc.Funcs {
+			fmt.Fprintf(&buf, "FN:%d,%s\n", f.StartLine, f.Name)
+		}
+		for _, f := range fc.Funcs {
+			n := funcHits(f, hits)
+			if n > 0 {
+				funcsHit++
+			}
+			fmt.Fprintf(&buf, "FNDA:%d,%s\n", n, f.Name)
+		}
+		fmt.Fprintf(&buf, "FNF:%d\n", len(fc.Funcs))
+		fmt.Fprintf(&buf, "FNH:%d\n", funcsHit)
+
+		var linesHit int
+		lines := sortedLines(hits)
+		for _, line := range lines {
+			if hits[line] > 0 {
+				linesHit++
+			}
+			fmt.Fprintf(&buf, "DA:%d,%d\n", line, hits[line])
+		}
+		fmt.Fprintf(&buf, "LF:%d\n", len(lines))
+		fmt.Fprintf(&buf, "LH:%d\n", linesHit)
+		buf.WriteString("end_of_record\n")
+	}
+	return buf.Bytes()
+}
+
+// writeLCOVReport writes the coverage report in LCOV format to the provided file.
+func writeLCOVReport(filename string, report *coverageReport, mod *goModule) error {
+	return os.WriteFile(filename, lcovReport(report, mod), 0644)
+}
+
+type coberturaCoverage struct {
+	XMLName         xml.Name            `xml:"coverage"`
+	LineRate        string              `xml:"line-rate,attr"`
+	BranchRate      string              `xml:"branch-rate,attr"`
+	LinesCovered    int                 `xml:"lines-covered,attr"`
+	LinesValid      int                 `xml:"lines-valid,attr"`
+	BranchesCovered int                 `xml:"branches-covered,attr"`
+	BranchesValid   int                 `xml:"branches-valid,attr"`
+	Complexity      int                 `xml:"complexity,attr"`
+	Version         string              `xml:"version,attr"`
+	Timestamp       int64               `xml:"timestamp,attr"`
+	Sources         []string            `xml:"sources>source"`
+	Packages        []*coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string            `xml:"name,attr"`
+	LineRate   string            `xml:"line-rate,attr"`
+	BranchRate string            `xml:"branch-rate,attr"`
+	Complexity int               `xml:"complexity,attr"`
+	Classes    []*coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string             `xml:"name,attr"`
+	Filename   string             `xml:"filename,attr"`
+	LineRate   string             `xml:"line-rate,attr"`
+	BranchRate string             `xml:"branch-rate,attr"`
+	Complexity int                `xml:"complexity,attr"`
+	Methods    []*coberturaMethod `xml:"methods>method"`
+	Lines      []*coberturaLine   `xml:"lines>line"`
+}
+
+type coberturaMethod struct {
+	Name       string           `xml:"name,attr"`
+	Signature  string           `xml:"signature,attr"`
+	LineRate   string           `xml:"line-rate,attr"`
+	BranchRate string           `xml:"branch-rate,attr"`
+	Complexity int              `xml:"complexity,attr"`
+	Lines      []*coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int `xml:"number,attr"`
+	Hits   int `xml:"hits,attr"`
+}
+
+// lineCounts accumulates the number of valid and covered lines.
+type lineCounts struct {
+	valid   int
+	covered int
+}
+
+func (lc *lineCounts) add(hits int) {
+	lc.valid++
+	if hits > 0 {
+		lc.covered++
+	}
+}
+
+func (lc *lineCounts) rate() string {
+	if lc.valid == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%.4g", float64(lc.covered)/float64(lc.valid))
+}
+
+// coberturaReport returns the coverage report in Cobertura XML format.
+func coberturaReport(report *coverageReport, mod *goModule) *coberturaCoverage {
+	source := "."
+	if mod != nil {
+		source = mod.Dir
+	}
+	cc := &coberturaCoverage{
+		BranchRate: "0",
+		Version:    "gocli",
+		Timestamp:  timeNow().UnixMilli(),
+		Sources:    []string{source},
+	}
+
+	var total lineCounts
+	pkgs := maps.Keys(report.Packages)
+	slices.Sort(pkgs)
+	for _, pkg := range pkgs {
+		cp := &coberturaPackage{Name: pkg, BranchRate: "0"}
+		var pkgCounts lineCounts
+		for _, fc := range report.Packages[pkg].Files {
+			hits := lineHits(fc.Blocks)
+			class := &coberturaClass{
+				Name:       path.Base(fc.Name),
+				Filename:   mod.relativePath(fc.Name),
+				BranchRate: "0",
+				Methods:    []*coberturaMethod{},
+			}
+
+			for _, f := range fc.Funcs {
+				m := &coberturaMethod{Name: f.Name, BranchRate: "0"}
+				var funcCounts lineCounts
+				for _, line := range sortedLines(hits) {
+					if line >= f.StartLine && line <= f.EndLine {
+						m.Lines = append(m.Lines, &coberturaLine{line, hits[line]})
+						funcCounts.add(hits[line])
+					}
+				}
+				m.LineRate = funcCounts.rate()
+				class.Methods = append(class.Methods, m)
+			}
+
+			var fileCounts lineCounts
+			for _, line := range sortedLines(hits) {
+				class.Lines = append(class.Lines, &coberturaLine{line, hits[line]})
+				fileCounts.add(hits[line])
+				pkgCounts.add(hits[line])
+				total.add(hits[line])
+			}
+			class.LineRate = fileCounts.rate()
+			cp.Classes = append(cp.Classes, class)
+		}
+		cp.LineRate = pkgCounts.rate()
+		cc.Packages = append(cc.Packages, cp)
+	}
+	cc.LineRate = total.rate()
+	cc.LinesCovered = total.covered
+	cc.LinesValid = total.valid
+	return cc
+}
+
+// writeCoberturaReport writes the coverage report in Cobertura XML format to
+// the provided file.
+func writeCoberturaReport(filename string, report *coverageReport, mod *goModule) error {
+	b, err := xml.MarshalIndent(coberturaReport(report, mod), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %v", err)
+	}
+	contents := append([]byte(xml.Header), b...)
+	return os.WriteFile(filename, append(contents, '\n'), 0644)
+}
@@ -0,0 +1,152 @@
+package gocli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/leep-frog/command/commandtest"
+)
+
+func exportTestReport(t *testing.T) (*coverageReport, *goModule) {
+	t.Helper()
+	mod := testModule(t)
+	cp := &coverProfile{
+		Mode: "count",
+		Files: map[string][]*coverBlock{
+			testCoverFile: {
+				{StartLine: 3, StartCol: 26, EndLine: 4, EndCol: 7, NumStmt: 1, Count: 1},
+				{StartLine: 4, StartCol: 7, EndLine: 6, EndCol: 3, NumStmt: 1, Count: 0},
+				{StartLine: 7, StartCol: 2, EndLine: 7, EndCol: 10, NumStmt: 1, Count: 1},
+				{StartLine: 12, StartCol: 26, EndLine: 14, EndCol: 2, NumStmt: 1, Count: 0},
+			},
+			testModulePath + "/p1/missing.go": {
+				{StartLine: 1, StartCol: 1, EndLine: 1, EndCol: 10, NumStmt: 1, Count: 2},
+			},
+		},
+	}
+	return computeCoverage(cp, mod), mod
+}
+
+func TestLCOVReport(t *testing.T) {
+	report, mod := exportTestReport(t)
+	filename := filepath.Join(t.TempDir(), "lcov.info")
+	if err := writeLCOVReport(filename, report, mod); err != nil {
+		t.Fatalf("writeLCOVReport() returned error: %v", err)
+	}
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read LCOV report: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"TN:",
+		"SF:p1/missing.go",
+		"FNF:0",
+		"FNH:0",
+		"DA:1,2",
+		"LF:1",
+		"LH:1",
+		"end_of_record",
+		"TN:",
+		"SF:testdata/cover/cover.go",
+		"FN:3,Covered",
+		"FN:12,(*thing).method",
+		"FNDA:1,Covered",
+		"FNDA:0,(*thing).method",
+		"FNF:2",
+		"FNH:1",
+		"DA:3,1",
+		"DA:4,1",
+		"DA:5,0",
+		"DA:6,0",
+		"DA:7,1",
+		"DA:12,0",
+		"DA:13,0",
+		"DA:14,0",
+		"LF:8",
+		"LH:3",
+		"end_of_record",
+		"",
+	}, "\n")
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("writeLCOVReport() wrote incorrect report (-want, +got):\n%s", diff)
+	}
+}
+
+func TestCoberturaReport(t *testing.T) {
+	commandtest.StubValue(t, &timeNow, func() time.Time {
+		return time.UnixMilli(1700000000000)
+	})
+	report, mod := exportTestReport(t)
+	filename := filepath.Join(t.TempDir(), "cobertura.xml")
+	if err := writeCoberturaReport(filename, report, mod); err != nil {
+		t.Fatalf("writeCoberturaReport() returned error: %v", err)
+	}
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read Cobertura report: %v", err)
+	}
+
+	want := strings.Join([]string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<coverage line-rate="0.4444" branch-rate="0" lines-covered="4" lines-valid="9" branches-covered="0" branches-valid="0" complexity="0" version="gocli" timestamp="1700000000000">`,
+		`  <sources>`,
+		`    <source>` + mod.Dir + `</source>`,
+		`  </sources>`,
+		`  <packages>`,
+		`    <package name="github.com/leep-frog/gocli/p1" line-rate="1" branch-rate="0" complexity="0">`,
+		`      <classes>`,
+		`        <class name="missing.go" filename="p1/missing.go" line-rate="1" branch-rate="0" complexity="0">`,
+		`          <methods></methods>`,
+		`          <lines>`,
+		`            <line number="1" hits="2"></line>`,
+		`          </lines>`,
+		`        </class>`,
+		`      </classes>`,
+		`    </package>`,
+		`    <package name="github.com/leep-frog/gocli/testdata/cover" line-rate="0.375" branch-rate="0" complexity="0">`,
+		`      <classes>`,
+		`        <class name="cover.go" filename="testdata/cover/cover.go" line-rate="0.375" branch-rate="0" complexity="0">`,
+		`          <methods>`,
+		`            <method name="Covered" signature="" line-rate="0.6" branch-rate="0" complexity="0">`,
+		`              <lines>`,
+		`                <line number="3" hits="1"></line>`,
+		`                <line number="4" hits="1"></line>`,
+		`                <line number="5" hits="0"></line>`,
+		`                <line number="6" hits="0"></line>`,
+		`                <line number="7" hits="1"></line>`,
+		`              </lines>`,
+		`            </method>`,
+		`            <method name="(*thing).method" signature="" line-rate="0" branch-rate="0" complexity="0">`,
+		`              <lines>`,
+		`                <line number="12" hits="0"></line>`,
+		`                <line number="13" hits="0"></line>`,
+		`                <line number="14" hits="0"></line>`,
+		`              </lines>`,
+		`            </method>`,
+		`          </methods>`,
+		`          <lines>`,
+		`            <line number="3" hits="1"></line>`,
+		`            <line number="4" hits="1"></line>`,
+		`            <line number="5" hits="0"></line>`,
+		`            <line number="6" hits="0"></line>`,
+		`            <line number="7" hits="1"></line>`,
+		`            <line number="12" hits="0"></line>`,
+		`            <line number="13" hits="0"></line>`,
+		`            <line number="14" hits="0"></line>`,
+		`          </lines>`,
+		`        </class>`,
+		`      </classes>`,
+		`    </package>`,
+		`  </packages>`,
+		`</coverage>`,
+		``,
+	}, "\n")
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("writeCoberturaReport() wrote incorrect report (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,253 @@
+package gocli
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/leep-frog/command/command"
+	"github.com/leep-frog/command/commander"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+var (
+	hunkHeaderRegex = regexp.MustCompile(`^@@ -[0-9]+(?:,[0-9]+)? \+([0-9]+)(?:,([0-9]+))? @@`)
+)
+
+// changedLines is a map from file (relative to the repository root) to the
+// sorted line numbers that were added or modified.
+type changedLines map[string][]int
+
+// gitChanges returns the repository root and the lines that changed between
+// the provided git ref and the working tree.
+func gitChanges(o command.Output, d *command.Data, ref string) (string, changedLines, error) {
+	root, err := (&commander.ShellCommand[[]string]{
+		CommandName: "git",
+		Args:        []string{"rev-parse", "--show-toplevel"},
+	}).Run(o, d)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to find git repository: %v", err)
+	}
+	if len(root) == 0 || root[0] == "" {
+		return "", nil, fmt.Errorf("failed to find git repository: no output from git")
+	}
+
+	// The prefixes are set explicitly since they can be changed by the user's
+	// git config (e.g. diff.noprefix or diff.mnemonicPrefix).
+	diff, err := (&commander.ShellCommand[[]string]{
+		CommandName: "git",
+		Args:        []string{"diff", "-U0", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/", ref, "--"},
+	}).Run(o, d)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get git diff: %v", err)
+	}
+	cl, err := parseGitDiff(diff)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// New files aren't included in the diff until they're added to git.
+	untracked, err := (&commander.ShellCommand[[]string]{
+		CommandName: "git",
+		Args:        []string{"ls-files", "--others", "--exclude-standard", "--full-name", "--", ":/"},
+	}).Run(o, d)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list untracked files: %v", err)
+	}
+	if err := addUntrackedFiles(cl, root[0], untracked); err != nil {
+		return "", nil, err
+	}
+	return root[0], cl, nil
+}
+
+// addUntrackedFiles adds every line of the provided go files (relative to the
+// repository root) to the changed lines.
+func addUntrackedFiles(cl changedLines, root string, files []string) error {
+	for _, file := range files {
+		if !strings.HasSuffix(file, ".go") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil {
+			return fmt.Errorf("failed to read untracked file: %v", err)
+		}
+		n := strings.Count(string(b), "\n")
+		if len(b) > 0 && !strings.HasSuffix(string(b), "\n") {
+			n++
+		}
+		cl[file] = nil
+		for line := 1; line <= n; line++ {
+			cl[file] = append(cl[file], line)
+		}
+	}
+	return nil
+}
+
+// parseGitDiff parses the lines that were added or modified from the output
+// of `git diff -U0`.
+func parseGitDiff(lines []string) (changedLines, error) {
+	cl := changedLines{}
+	var file, prev string
+	for _, line := range lines {
+		// File headers are always a `---` line followed by a `+++` line (checking
+		// both avoids confusing added lines that start with `++` for a header).
+		isHeader := strings.HasPrefix(prev, "--- ")
+		prev = line
+		if name, ok := strings.CutPrefix(line, "+++ "); ok && isHeader {
+			file = ""
+			if name == "/dev/null" {
+				// The file was deleted.
+				continue
+			}
+			if strings.HasPrefix(name, `"`) {
+				unquoted, err := strconv.Unquote(name)
+				if err != nil {
+					return nil, fmt.Errorf("invalid file name in git diff: %s", name)
+				}
+				name = unquoted
+			}
+			file = strings.TrimPrefix(name, "b/")
+			continue
+		}
+
+		m := hunkHeaderRegex.FindStringSubmatch(line)
+		if m == nil || file == "" {
+			continue
+		}
+		start, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid hunk header in git diff: %s", line)
+		}
+		count := 1
+		if m[2] != "" {
+			if count, err = strconv.Atoi(m[2]); err != nil {
+				return nil, fmt.Errorf("invalid hunk header in git diff: %s", line)
+			}
+		}
+		for i := 0; i < count; i++ {
+			cl[file] = append(cl[file], start+i)
+		}
+	}
+	for _, nums := range cl {
+		slices.Sort(nums)
+	}
+	return cl, nil
+}
+
+// diffCoverage is the coverage of the executable lines that were changed.
+type diffCoverage struct {
+	// Counts contains the number of changed executable lines (as Statements)
+	// and the number of those lines that were covered.
+	Counts coverageCounts
+	// Uncovered are the changed executable lines that weren't covered,
+	// formatted as `file:line`.
+	Uncovered []string
+}
+
+// codeLines returns the lines in a go source file that contain code (i.e.
+// anything other than comments and braces). If the file can't be read, nil is
+// returned.
+func codeLines(filename string) map[int]bool {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+	fset := token.NewFileSet()
+	file := fset.AddFile(filename, -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, 0)
+
+	lines := map[int]bool{}
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		switch {
+		case tok == token.LBRACE, tok == token.RBRACE:
+		// Semicolons inserted at the end of a line aren't code.
+		case tok == token.SEMICOLON && lit == "\n":
+		default:
+			lines[fset.Position(pos).Line] = true
+		}
+	}
+	return lines
+}
+
+// computeDiffCoverage intersects the changed lines with the coverage profile
+// blocks. A changed line is executable if it contains code and is part of any
+// block with statements, and is covered if any of those blocks was run.
+func computeDiffCoverage(report *coverageReport, root string, cl changedLines) *diffCoverage {
+	dc := &diffCoverage{}
+	pkgs := maps.Keys(report.Packages)
+	slices.Sort(pkgs)
+	for _, pkg := range pkgs {
+		for _, fc := range report.Packages[pkg].Files {
+			if fc.Path == "" {
+				continue
+			}
+			rel, err := filepath.Rel(root, fc.Path)
+			if err != nil {
+				continue
+			}
+			rel = filepath.ToSlash(rel)
+			if len(cl[rel]) == 0 {
+				continue
+			}
+			code := codeLines(fc.Path)
+			for _, line := range cl[rel] {
+				executable, covered := lineCoverage(fc.Blocks, line)
+				if !executable || (code != nil && !code[line]) {
+					continue
+				}
+				dc.Counts.Statements++
+				if covered {
+					dc.Counts.Covered++
+				} else {
+					dc.Uncovered = append(dc.Uncovered, fmt.Sprintf("%s:%d", rel, line))
+				}
+			}
+		}
+	}
+	return dc
+}
+
+func lineCoverage(blocks []*coverBlock, line int) (executable bool, covered bool) {
+	for _, b := range blocks {
+		if b.NumStmt == 0 || line < b.StartLine || line > b.EndLine {
+			continue
+		}
+		executable = true
+		if b.Count > 0 {
+			covered = true
+		}
+	}
+	return executable, covered
+}
+
+// checkDiffCoverage prints the diff coverage and returns an error if it is
+// below the provided minimum.
+func checkDiffCoverage(o command.Output, dc *diffCoverage, mc float64) error {
+	if dc.Counts.Statements == 0 {
+		o.Stdoutln("No changed executable lines")
+		return nil
+	}
+
+	o.Stdoutf("Changed line coverage: %s (%d/%d lines)\n", percentFormat(dc.Counts.Percent()), dc.Counts.Covered, dc.Counts.Statements)
+	if len(dc.Uncovered) > 0 {
+		o.Stdoutln("Uncovered changed lines:")
+		for _, line := range dc.Uncovered {
+			o.Stdoutf("  %s\n", line)
+		}
+	}
+	if dc.Counts.Percent() < mc {
+		return o.Stderrf("Coverage of changed lines (%s) must be at least %s\n", percentFormat(dc.Counts.Percent()), percentFormat(mc))
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/leep-frog/command/command"
+	"github.com/leep-frog/command/commandertest"
+	"github.com/leep-frog/command/commandtest"
+)
+
+func TestParseGitDiff(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		lines   []string
+		want    changedLines
+		wantErr error
+	}{
+		{
+			name: "handles empty diff",
+			want: changedLines{},
+		},
+		{
+			name: "parses added and modified lines",
+			lines: []string{
+				"diff --git a/p1/file.go b/p1/file.go",
+				"index 1234567..89abcde 100644",
+				"--- a/p1/file.go",
+				"+++ b/p1/file.go",
+				"@@ -10,0 +11,2 @@ func F() {",
+				"+	a()",
+				"+++	b()",
+				"@@ -3 +3 @@ package p1",
+				"-old",
+				"+new",
+				"@@ -20,2 +21,0 @@ func G() {",
+				"-	removed()",
+				"-	removed()",
+				"diff --git a/old.go b/old.go",
+				"deleted file mode 100644",
+				"--- a/old.go",
+				"+++ /dev/null",
+				"@@ -1,2 +0,0 @@",
+				"-package p1",
+				"-",
+				"diff --git \"a/with space.go\" \"b/with space.go\"",
+				"--- \"a/with space.go\"",
+				"+++ \"b/with space.go\"",
+				"@@ -0,0 +1 @@",
+				"+package p1",
+			},
+			want: changedLines{
+				"p1/file.go":    {3, 11, 12},
+				"with space.go": {1},
+			},
+		},
+		{
+			name: "only strips the diff prefix",
+			lines: []string{
+				"diff --git a/b/file.go b/b/file.go",
+				"--- a/b/file.go",
+				"+++ b/b/file.go",
+				"@@ -1 +1 @@",
+				"-old",
+				"+new",
+			},
+			want: changedLines{
+				"b/file.go": {1},
+			},
+		},
+		{
+			name: "fails if invalid quoted file name",
+			lines: []string{
+				"--- \"a/bad",
+				"+++ \"b/bad",
+			},
+			wantErr: fmt.Errorf("invalid file name in git diff: \"b/bad"),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseGitDiff(test.lines)
+			if diff := cmp.Diff(test.wantErr, err, cmpErrors()); diff != "" {
+				t.Errorf("parseGitDiff() returned incorrect error (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("parseGitDiff() returned incorrect lines (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestComputeDiffCoverage(t *testing.T) {
+	mod := testModule(t)
+	cp := &coverProfile{
+		Mode: "set",
+		Files: map[string][]*coverBlock{
+			testCoverFile: {
+				{StartLine: 3, StartCol: 20, EndLine: 5, EndCol: 3, NumStmt: 2, Count: 1},
+				{StartLine: 5, StartCol: 3, EndLine: 7, EndCol: 2, NumStmt: 1, Count: 0},
+				{StartLine: 12, StartCol: 25, EndLine: 14, EndCol: 2, NumStmt: 1, Count: 0},
+			},
+			"example.com/other/file.go": {
+				{StartLine: 1, StartCol: 1, EndLine: 10, EndCol: 1, NumStmt: 1, Count: 0},
+			},
+		},
+	}
+	report := computeCoverage(cp, mod)
+
+	cl := changedLines{
+		"testdata/cover/cover.go": {1, 4, 5, 6, 13, 20},
+		"file.go":                 {1},
+	}
+	// Line 6 only contains a closing brace, so it isn't executable.
+	want := &diffCoverage{
+		Counts:    coverageCounts{Statements: 3, Covered: 2},
+		Uncovered: []string{"testdata/cover/cover.go:13"},
+	}
+	if diff := cmp.Diff(want, computeDiffCoverage(report, mod.Dir, cl)); diff != "" {
+		t.Errorf("computeDiffCoverage() returned incorrect coverage (-want, +got):\n%s", diff)
+	}
+}
+
+func TestUntrackedFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "p1", "new.go"), "package p1\n\nfunc New() {}")
+	writeFile(t, filepath.Join(root, "notes.txt"), "not go\n")
+
+	cl := changedLines{"p1/old.go": {3}}
+	if err := addUntrackedFiles(cl, root, []string{"p1/new.go", "notes.txt"}); err != nil {
+		t.Fatalf("addUntrackedFiles() returned error: %v", err)
+	}
+	want := changedLines{
+		"p1/old.go": {3},
+		"p1/new.go": {1, 2, 3},
+	}
+	if diff := cmp.Diff(want, cl); diff != "" {
+		t.Errorf("addUntrackedFiles() produced incorrect lines (-want, +got):\n%s", diff)
+	}
+
+	if err := addUntrackedFiles(cl, root, []string{"missing.go"}); err == nil {
+		t.Errorf("addUntrackedFiles(missing file) returned nil error; want error")
+	}
+}
+
+func TestDiffBaseFlag(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	pkg := testModulePath + "/p1"
+	diffLines := []string{
+		"--- a/p1/file.go",
+		"+++ b/p1/file.go",
+		"@@ -3,0 +4,4 @@",
+	}
+
+	for _, test := range []struct {
+		name        string
+		minCoverage float64
+		covered     int
+		wantStdout  []string
+		wantErr     error
+		wantStderr  string
+		// recordsHistory is whether the run records coverage history (which
+		// runs `git status` after `go test`).
+		recordsHistory bool
+	}{
+		{
+			name:           "passes if changed line coverage is above threshold",
+			minCoverage:    50,
+			recordsHistory: true,
+			covered:        5,
+			wantStdout: []string{
+				"Changed line coverage: 50.0% (2/4 lines)",
+				"Uncovered changed lines:",
+				"  p1/file.go:6",
+				"  p1/file.go:7",
+			},
+		},
+		{
+			name:        "fails if changed line coverage is below threshold",
+			minCoverage: 60,
+			covered:     4,
+			wantStdout: []string{
+				"Changed line coverage: 25.0% (1/4 lines)",
+				"Uncovered changed lines:",
+				"  p1/file.go:5",
+				"  p1/file.go:6",
+				"  p1/file.go:7",
+			},
+			wantErr:    fmt.Errorf("Coverage of changed lines (25.0%%) must be at least 60.0%%"),
+			wantStderr: "Coverage of changed lines (25.0%) must be at least 60.0%\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			coverProfile := filepath.Join(t.TempDir(), "cover.out")
+			writeFile(t, coverProfile, strings.Join(profileLines("set", profileBlocks(pkg, test.covered, 10)), "\n"))
+			commandtest.StubValue(t, &tmpFile, func() (*os.File, error) {
+				return os.Open(coverProfile)
+			})
+
+			coverage := float64(test.covered * 10)
+			runResponses := []*commandtest.FakeRun{
+				{Stdout: []string{wd}},
+				{Stdout: diffLines},
+				{},
+				{Stdout: successEvents(pkg, coverage)},
+			}
+			wantRunContents := []*commandtest.RunContents{
+				{Name: "git", Args: []string{"rev-parse", "--show-toplevel"}},
+				{Name: "git", Args: []string{"diff", "-U0", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/", "main", "--"}},
+				{Name: "git", Args: []string{"ls-files", "--others", "--exclude-standard", "--full-name", "--", ":/"}},
+				{Name: "go", Args: []string{"test", "-json", ".", fmt.Sprintf("-coverprofile=%s", coverProfile)}},
+			}
+			if test.recordsHistory {
+				runResponses = append(runResponses, gitStatusRun("head"))
+				wantRunContents = append(wantRunContents, gitStatusContents(t))
+			}
+			commandertest.ExecuteTest(t, &commandtest.ExecuteTestCase{
+				Node:            CLI().Node(),
+				Args:            []string{"-d", "main", "-m", fmt.Sprintf("%g", test.minCoverage)},
+				RunResponses:    runResponses,
+				WantStdout:      stdoutLines(append([]string{successOutput(pkg, coverage)}, test.wantStdout...)...),
+				WantErr:         test.wantErr,
+				WantStderr:      test.wantStderr,
+				WantRunContents: wantRunContents,
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): test.minCoverage,
+					diffBaseFlag.Name():    "main",
+					"COVERAGE": map[string]*packageResult{
+						pkg: {
+							TestResult: testSuccess,
+							Coverage:   coverage,
+							Line:       successOutput(pkg, coverage),
+						},
+					},
+				}},
+			})
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package gocli
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/exp/slices"
+)
+
+// testFuncKind is the kind of function that `go test` runs.
+type testFuncKind int
+
+const (
+	testKindTest testFuncKind = iota
+	testKindBenchmark
+	testKindFuzz
+	testKindExample
+)
+
+var testFuncKindNames = map[testFuncKind]string{
+	testKindTest:      "test",
+	testKindBenchmark: "benchmark",
+	testKindFuzz:      "fuzz",
+	testKindExample:   "example",
+}
+
+func (k testFuncKind) String() string {
+	if s, ok := testFuncKindNames[k]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown(%d)", int(k))
+}
+
+// testFunc is a test, benchmark, fuzz target, or example function.
+type testFunc struct {
+	Kind testFuncKind
+	// Name is the full function name (e.g. `TestExecute`).
+	Name string
+	// File is the path to the file that contains the function.
+	File string
+	Line int
+	// Subtests are the (mangled) names of any subtests that could be found.
+	Subtests []string
+}
+
+// testPackage contains the test functions of a single package directory
+// (including those in the external `_test` package).
+type testPackage struct {
+	// ImportPath is the package import path (or empty if the package isn't in
+	// the current module).
+	ImportPath string
+	Dir        string
+	// Funcs are the test functions, sorted by file and line.
+	Funcs []*testFunc
+	// Err is the error encountered while loading the package (if any). Errors
+	// are per-package so one broken directory doesn't hide the tests in
+	// every other package.
+	Err error
+}
+
+// isTestName returns whether or not the function name is a valid name for a
+// function with the provided prefix (see `go help testfunc`).
+func isTestName(name, prefix string) bool {
+	rest, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return false
+	}
+	if rest == "" {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return !unicode.IsLower(r)
+}
+
+// testingParam returns the type name of the function's parameter if the
+// function has a single parameter that is a pointer to a type in the testing
+// package (e.g. `T` for `*testing.T`).
+func testingParam(fd *ast.FuncDecl) string {
+	params := fd.Type.Params.List
+	if len(params) != 1 || len(params[0].Names) > 1 {
+		return ""
+	}
+	star, ok := params[0].Type.(*ast.StarExpr)
+	if !ok {
+		return ""
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "testing" {
+		return ""
+	}
+	return sel.Sel.Name
+}
+
+// testFuncKindOf returns the kind of test function (and false if the function
+// isn't run by `go test`).
+func testFuncKindOf(fd *ast.FuncDecl) (testFuncKind, bool) {
+	if fd.Recv != nil || fd.Body == nil || fd.Type.TypeParams != nil {
+		return 0, false
+	}
+	name := fd.Name.Name
+	switch param := testingParam(fd); {
+	case param == "T" && isTestName(name, "Test"):
+		return testKindTest, true
+	case param == "B" && isTestName(name, "Benchmark"):
+		return testKindBenchmark, true
+	case param == "F" && isTestName(name, "Fuzz"):
+		return testKindFuzz, true
+	case len(fd.Type.Params.List) == 0 && fd.Type.Results == nil && isTestName(name, "Example"):
+		return testKindExample, true
+	}
+	return 0, false
+}
+
+// parseTestFile returns the test functions in a test file. Files with syntax
+// errors (e.g. ones that are being edited) are parsed as much as possible.
+func parseTestFile(filename string) ([]*testFunc, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, 0)
+	if f == nil {
+		return nil, fmt.Errorf("failed to parse test file: %v", err)
+	}
+
+	var funcs []*testFunc
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		kind, ok := testFuncKindOf(fd)
+		if !ok {
+			continue
+		}
+		tf := &testFunc{
+			Kind: kind,
+			Name: fd.Name.Name,
+			File: filename,
+			Line: fset.Position(fd.Pos()).Line,
+		}
+		if kind == testKindTest || kind == testKindFuzz {
+			tf.Subtests = subtestNames(fd)
+		}
+		funcs = append(funcs, tf)
+	}
+	return funcs, nil
+}
+
+// discoverTests returns the test functions in the package in the provided
+// directory (or nil if the directory doesn't contain a go package). Only
+// files that satisfy the build constraints of the default build context are
+// included. If the package can't be loaded, the returned package's Err is set.
+func discoverTests(dir string, mod *goModule) *testPackage {
+	tp := &testPackage{
+		ImportPath: mod.importPath(dir),
+		Dir:        dir,
+	}
+	bp, err := build.ImportDir(dir, 0)
+	var noGo *build.NoGoError
+	if errors.As(err, &noGo) {
+		return nil
+	} else if err != nil {
+		tp.Err = fmt.Errorf("failed to load package in %s: %v", dir, err)
+		return tp
+	}
+
+	for _, name := range append(append([]string{}, bp.TestGoFiles...), bp.XTestGoFiles...) {
+		funcs, err := parseTestFile(filepath.Join(dir, name))
+		if err != nil {
+			tp.Err = err
+			continue
+		}
+		tp.Funcs = append(tp.Funcs, funcs...)
+	}
+	slices.SortFunc(tp.Funcs, func(a, b *testFunc) int {
+		if a.File != b.File {
+			return strings.Compare(a.File, b.File)
+		}
+		return a.Line - b.Line
+	})
+	return tp
+}
+
+// hasTestFiles returns whether the package in the provided directory has any
+// test files (i.e. whether `go test` would report it as having no test files).
+// Directories that can't be loaded are assumed to have test files.
+func hasTestFiles(dir string) bool {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return true
+	}
+	return len(bp.TestGoFiles)+len(bp.XTestGoFiles) > 0
+}
+
+// markNoTestFiles marks the passing packages in the module that don't have any
+// test files. With coverage enabled, go 1.22+ reports those packages as
+// passing (rather than skipped), and coverage profiles include them.
+func markNoTestFiles(results map[string]*packageResult, mod *goModule) {
+	for p, pr := range results {
+		if pr.TestResult != testSuccess {
+			continue
+		}
+		if dir := mod.sourcePath(p + "/"); dir != "" && !hasTestFiles(dir) {
+			pr.TestResult = noTestFiles
+		}
+	}
+}
+
+// findTestPackages returns the test packages matched by the provided paths.
+// Paths ending in `/...` match every package in the directory tree (other
+// than directories that the go command ignores, like testdata, and nested
+// modules).
+func findTestPackages(paths []string, mod *goModule) ([]*testPackage, error) {
+	var dirs []string
+	for _, p := range paths {
+		root, recursive := strings.CutSuffix(p, "...")
+		root = strings.TrimSuffix(root, "/")
+		if root == "" {
+			root = "."
+		}
+		if mod != nil && (root == mod.Path || strings.HasPrefix(root, mod.Path+"/")) {
+			root = mod.sourcePath(root + "/")
+		}
+		if !recursive {
+			dirs = append(dirs, filepath.Clean(root))
+			continue
+		}
+
+		if err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// Unreadable subdirectories are skipped (like other directories
+				// that can't be loaded).
+				if path != root && d != nil && d.IsDir() {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			if path != root {
+				if name := d.Name(); name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+					return filepath.SkipDir
+				}
+				if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+					return filepath.SkipDir
+				}
+			}
+			dirs = append(dirs, filepath.Clean(path))
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("failed to find packages in %s: %v", root, err)
+		}
+	}
+
+	var r []*testPackage
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path: %v", err)
+		}
+		if seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		if tp := discoverTests(dir, mod); tp != nil {
+			r = append(r, tp)
+		}
+	}
+	return r, nil
+}
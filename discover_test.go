@@ -0,0 +1,202 @@
+package gocli
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIsTestName(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		want bool
+	}{
+		{name: "Test", want: true},
+		{name: "TestFoo", want: true},
+		{name: "Test_foo", want: true},
+		{name: "TestÜber", want: true},
+		{name: "Testify"},
+		{name: "Tes"},
+	} {
+		if got := isTestName(test.name, "Test"); got != test.want {
+			t.Errorf("isTestName(%q) returned %v; want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDiscoverTests(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "x.go"), "package x\n")
+	writeFile(t, filepath.Join(dir, "x_test.go"), `package x
+
+import "testing"
+
+func TestTable(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		want int
+	}{
+		{name: "simple case", want: 1},
+		{name: "tab\tand.dot", want: 2},
+		{name: "simple case", want: 3},
+	} {
+		t.Run(test.name, func(t *testing.T) {})
+	}
+}
+
+func TestMultiLine(
+	t *testing.T,
+) {
+	t.Run("literal", func(t *testing.T) {})
+	t.Run(name(), func(t *testing.T) {})
+}
+
+// func TestCommentedOut(t *testing.T) {}
+
+func TestMain(m *testing.M) {}
+
+func Testify(t *testing.T) {}
+
+func TestNotATest(s string) {}
+
+func BenchmarkX(b *testing.B) {}
+
+func FuzzX(f *testing.F) {
+	f.Fuzz(func(t *testing.T, s string) {})
+}
+
+func helper(t *testing.T) {}
+
+func name() string { return "" }
+
+func TestTableLiterals(t *testing.T) {
+	_ = config{Name: "p1"}
+	_ = thing{name: "solo"}
+	_ = map[string]struct{ name string }{
+		"key": {name: "from map"},
+	}
+	_ = []*thing{{Name: "upper"}, {name: "lower"}}
+}
+`)
+	writeFile(t, filepath.Join(dir, "x_ext_test.go"), `package x_test
+
+import "fmt"
+
+func Example() {
+	fmt.Println("hi")
+	// Output: hi
+}
+
+func ExampleX_suffix() {}
+
+func ExampleWithResult() int { return 0 }
+`)
+	writeFile(t, filepath.Join(dir, "ignored_test.go"), `//go:build ignore
+
+package x
+
+import "testing"
+
+func TestIgnored(t *testing.T) {}
+`)
+
+	got := discoverTests(dir, nil)
+	testFile, extFile := filepath.Join(dir, "x_test.go"), filepath.Join(dir, "x_ext_test.go")
+	want := &testPackage{
+		Dir: dir,
+		Funcs: []*testFunc{
+			{Kind: testKindExample, Name: "Example", File: extFile, Line: 5},
+			{Kind: testKindExample, Name: "ExampleX_suffix", File: extFile, Line: 10},
+			{Kind: testKindTest, Name: "TestTable", File: testFile, Line: 5, Subtests: []string{"simple_case", "tab_and.dot"}},
+			{Kind: testKindTest, Name: "TestMultiLine", File: testFile, Line: 18, Subtests: []string{"literal"}},
+			{Kind: testKindBenchmark, Name: "BenchmarkX", File: testFile, Line: 33},
+			{Kind: testKindFuzz, Name: "FuzzX", File: testFile, Line: 35},
+			{Kind: testKindTest, Name: "TestTableLiterals", File: testFile, Line: 43, Subtests: []string{"from_map", "lower"}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("discoverTests() returned incorrect package (-want, +got):\n%s", diff)
+	}
+
+	if got := discoverTests(t.TempDir(), nil); got != nil {
+		t.Errorf("discoverTests(empty dir) returned %v; want nil", got)
+	}
+}
+
+func TestFindTestPackagesErrors(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "good", "good_test.go"), "package good\n\nimport \"testing\"\n\nfunc TestGood(t *testing.T) {}\n")
+	writeFile(t, filepath.Join(root, "multi", "a.go"), "package a\n")
+	writeFile(t, filepath.Join(root, "multi", "b.go"), "package b\n")
+	writeFile(t, filepath.Join(root, "editing", "editing_test.go"), "package editing\n\nimport (\n\t\"testing\"\n")
+	writeFile(t, filepath.Join(root, "nested", "go.mod"), "module nested\n")
+	writeFile(t, filepath.Join(root, "nested", "nested_test.go"), "package nested\n\nimport \"testing\"\n\nfunc TestNested(t *testing.T) {}\n")
+
+	pkgs, err := findTestPackages([]string{root + "/..."}, nil)
+	if err != nil {
+		t.Fatalf("findTestPackages() returned error: %v", err)
+	}
+	got := map[string]string{}
+	for _, tp := range pkgs {
+		var names []string
+		for _, tf := range tp.Funcs {
+			names = append(names, tf.Name)
+		}
+		status := strings.Join(names, ",")
+		if tp.Err != nil {
+			status = "error"
+		}
+		got[filepath.Base(tp.Dir)] = status
+	}
+	want := map[string]string{
+		"editing": "error",
+		"good":    "TestGood",
+		"multi":   "error",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("findTestPackages() returned incorrect packages (-want, +got):\n%s", diff)
+	}
+}
+
+func TestFindTestPackages(t *testing.T) {
+	mod := testModule(t)
+	for _, test := range []struct {
+		name  string
+		paths []string
+		want  []string
+	}{
+		{
+			name:  "finds package in directory",
+			paths: []string{"./testpkg"},
+			want:  []string{testModulePath + "/testpkg"},
+		},
+		{
+			name:  "finds packages recursively",
+			paths: []string{"./..."},
+			want:  []string{testModulePath, testModulePath + "/testpkg"},
+		},
+		{
+			name:  "finds packages by import path",
+			paths: []string{testModulePath + "/...", "./testpkg"},
+			want:  []string{testModulePath, testModulePath + "/testpkg"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			pkgs, err := findTestPackages(test.paths, mod)
+			if err != nil {
+				t.Fatalf("findTestPackages(%v) returned error: %v", test.paths, err)
+			}
+			var got []string
+			for _, tp := range pkgs {
+				if len(tp.Funcs) > 0 {
+					got = append(got, tp.ImportPath)
+				}
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("findTestPackages(%v) returned incorrect packages (-want, +got):\n%s", test.paths, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package gocli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leep-frog/command/command"
+)
+
+// testEvent is a single event emitted by `go test -json` (see `go doc test2json`).
+type testEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+	// ImportPath is the package being built (only set for build events).
+	ImportPath string
+	// FailedBuild is the ImportPath of the build that caused the package to
+	// fail (only set for package fail events).
+	FailedBuild string
+}
+
+const (
+	actionPass        = "pass"
+	actionFail        = "fail"
+	actionSkip        = "skip"
+	actionOutput      = "output"
+	actionBuildOutput = "build-output"
+)
+
+// testStatus is the status of an individual test function (or subtest).
+type testStatus int
+
+const (
+	testRunning testStatus = iota
+	testPassed
+	testFailed
+	testSkipped
+)
+
+// testCaseResult is the result of an individual test function (or subtest).
+type testCaseResult struct {
+	// Name is the full name of the test (e.g. `TestExecute/Some_case`).
+	Name   string
+	Status testStatus
+	// Elapsed is the test duration in seconds.
+	Elapsed float64
+	// Output is the list of output lines produced by the test.
+	Output []string
+	// Subtests are the results of any subtests run by this test, in the order
+	// in which they were started.
+	Subtests []*testCaseResult
+}
+
+// packageTests contains the results of all tests run for a single package.
+type packageTests struct {
+	// Tests are the top-level test results in the order in which the tests were started.
+	Tests []*testCaseResult
+	// all contains every test (including subtests) in the order in which they were started.
+	all    []*testCaseResult
+	byName map[string]*testCaseResult
+}
+
+func (pt *packageTests) get(name string) *testCaseResult {
+	if tcr, ok := pt.byName[name]; ok {
+		return tcr
+	}
+	tcr := &testCaseResult{Name: name}
+	pt.byName[name] = tcr
+	pt.all = append(pt.all, tcr)
+	if parent := pt.parent(name); parent != nil {
+		parent.Subtests = append(parent.Subtests, tcr)
+	} else {
+		pt.Tests = append(pt.Tests, tcr)
+	}
+	return tcr
+}
+
+// parent returns the closest ancestor test of the provided test name. Subtest
+// names can contain slashes themselves, so every prefix is checked.
+func (pt *packageTests) parent(name string) *testCaseResult {
+	for i := strings.LastIndex(name, "/"); i >= 0; i = strings.LastIndex(name[:i], "/") {
+		if tcr, ok := pt.byName[name[:i]]; ok {
+			return tcr
+		}
+	}
+	return nil
+}
+
+// testFramingPrefixes are the prefixes of output lines that only indicate
+// test progress and are hidden from non-verbose output.
+var testFramingPrefixes = []string{
+	"=== RUN",
+	"=== PAUSE",
+	"=== CONT",
+	"=== NAME",
+}
+
+func isFramingLine(line string) bool {
+	for _, prefix := range testFramingPrefixes {
+		if strings.HasPrefix(strings.TrimSpace(line), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHiddenPackageLine returns whether or not the package-level output line
+// is hidden when not running in verbose mode (`go test` only shows these
+// lines when run with `-v`).
+func isHiddenPackageLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "PASS" || strings.HasPrefix(trimmed, "coverage: ")
+}
+
+type goTestEventHandler struct {
+	verbose bool
+	// quiet indicates that no test output should be streamed.
+	quiet          bool
+	packageResults map[string]*packageResult
+	packageTests   map[string]*packageTests
+	// summaryLines is a map from package to the most recent package-level output line.
+	summaryLines map[string]string
+	// packageOutput is a map from package to all package-level output lines.
+	packageOutput map[string][]string
+	// buildOutput is a map from build import path to the build's output lines.
+	buildOutput map[string][]string
+	// failedBuilds is a map from package to the import path of the build that
+	// caused the package to fail.
+	failedBuilds map[string]string
+	// printed is the set of unfinished tests whose output has already been printed.
+	printed map[*testCaseResult]bool
+	// partial is the output that has been received, but not yet terminated by a newline.
+	partial []byte
+	err     error
+}
+
+func newGoTestEventHandler(verbose, quiet bool) *goTestEventHandler {
+	return &goTestEventHandler{
+		verbose:        verbose,
+		quiet:          quiet,
+		packageResults: map[string]*packageResult{},
+		packageTests:   map[string]*packageTests{},
+		summaryLines:   map[string]string{},
+		packageOutput:  map[string][]string{},
+		buildOutput:    map[string][]string{},
+		failedBuilds:   map[string]string{},
+		printed:        map[*testCaseResult]bool{},
+	}
+}
+
+func (eh *goTestEventHandler) streamFunc(output command.Output, data *command.Data, bLine []byte) error {
+	if eh.err != nil {
+		return nil
+	}
+
+	eh.partial = append(eh.partial, bLine...)
+	for {
+		idx := bytes.IndexByte(eh.partial, '\n')
+		if idx < 0 {
+			break
+		}
+		line := string(eh.partial[:idx])
+		eh.partial = eh.partial[idx+1:]
+		if eh.err = eh.processLine(output, line); eh.err != nil {
+			break
+		}
+	}
+
+	return nil
+}
+
+// flush processes any remaining output that wasn't terminated by a newline.
+func (eh *goTestEventHandler) flush(output command.Output) {
+	if eh.err != nil || len(eh.partial) == 0 {
+		return
+	}
+	line := string(eh.partial)
+	eh.partial = nil
+	eh.err = eh.processLine(output, line)
+}
+
+func (eh *goTestEventHandler) processLine(output command.Output, line string) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+
+	e := &testEvent{}
+	if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), e) != nil {
+		// Not a test2json event (e.g. output from go itself), so just forward it.
+		eh.println(output, line)
+		// Older versions of go report build failures as plain text.
+		if m := failedPackageRegex.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			eh.summaryLines[m[1]] = strings.TrimSpace(line)
+			return eh.setPackageResult(&testEvent{Package: m[1]}, testFailure)
+		}
+		return nil
+	}
+	return eh.processEvent(output, e)
+}
+
+func (eh *goTestEventHandler) packageTestsFor(pkg string) *packageTests {
+	pt, ok := eh.packageTests[pkg]
+	if !ok {
+		pt = &packageTests{byName: map[string]*testCaseResult{}}
+		eh.packageTests[pkg] = pt
+	}
+	return pt
+}
+
+func (eh *goTestEventHandler) processEvent(output command.Output, e *testEvent) error {
+	if e.Test != "" {
+		eh.processTestEvent(output, e)
+		return nil
+	}
+
+	switch e.Action {
+	case actionBuildOutput:
+		for _, line := range splitOutput(e.Output) {
+			eh.buildOutput[e.ImportPath] = append(eh.buildOutput[e.ImportPath], line)
+			eh.println(output, line)
+		}
+	case actionOutput:
+		eh.printUnfinishedTests(output, e.Package)
+		for _, line := range splitOutput(e.Output) {
+			eh.packageOutput[e.Package] = append(eh.packageOutput[e.Package], line)
+			if strings.TrimSpace(line) != "" {
+				eh.summaryLines[e.Package] = line
+			}
+			if eh.verbose || !isHiddenPackageLine(line) {
+				eh.println(output, line)
+			}
+		}
+	case actionPass:
+		return eh.setPackageResult(e, testSuccess)
+	case actionFail:
+		eh.printUnfinishedTests(output, e.Package)
+		if e.FailedBuild != "" {
+			eh.failedBuilds[e.Package] = e.FailedBuild
+		}
+		return eh.setPackageResult(e, testFailure)
+	case actionSkip:
+		return eh.setPackageResult(e, noTestFiles)
+	}
+	return nil
+}
+
+func (eh *goTestEventHandler) processTestEvent(output command.Output, e *testEvent) {
+	tcr := eh.packageTestsFor(e.Package).get(e.Test)
+	switch e.Action {
+	case actionOutput:
+		lines := splitOutput(e.Output)
+		tcr.Output = append(tcr.Output, lines...)
+		if eh.verbose {
+			for _, line := range lines {
+				eh.println(output, line)
+			}
+		}
+	case actionPass:
+		tcr.Status = testPassed
+		tcr.Elapsed = e.Elapsed
+	case actionSkip:
+		tcr.Status = testSkipped
+		tcr.Elapsed = e.Elapsed
+	case actionFail:
+		tcr.Status = testFailed
+		tcr.Elapsed = e.Elapsed
+		if !eh.verbose {
+			eh.printTestOutput(output, tcr)
+		}
+	}
+}
+
+// printUnfinishedTests prints the output of any tests that haven't completed
+// by the time package-level output is produced (which happens when a test
+// panics or times out).
+func (eh *goTestEventHandler) printUnfinishedTests(output command.Output, pkg string) {
+	if eh.verbose {
+		return
+	}
+	for _, tcr := range eh.packageTestsFor(pkg).all {
+		if tcr.Status == testRunning && !eh.printed[tcr] {
+			eh.printed[tcr] = true
+			eh.printTestOutput(output, tcr)
+		}
+	}
+}
+
+func (eh *goTestEventHandler) println(output command.Output, line string) {
+	if !eh.quiet {
+		output.Stdoutln(line)
+	}
+}
+
+func (eh *goTestEventHandler) printTestOutput(output command.Output, tcr *testCaseResult) {
+	for _, line := range tcr.Output {
+		if !isFramingLine(line) {
+			eh.println(output, line)
+		}
+	}
+}
+
+// splitOutput splits the output of a test event into separate lines.
+func splitOutput(s string) []string {
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func (eh *goTestEventHandler) setPackageResult(e *testEvent, tr testResult) error {
+	pkg := e.Package
+	line := eh.summaryLines[pkg]
+	if r, ok := eh.packageResults[pkg]; ok {
+		return fmt.Errorf("Multiple results for package %q:\n  Result 1: %s\n  Result 2: %s", pkg, r.Line, line)
+	}
+
+	eh.packageResults[pkg] = &packageResult{
+		TestResult: tr,
+		Line:       line,
+		Elapsed:    e.Elapsed,
+	}
+	return nil
+}
+
+// testResults returns a map from package to the top-level test results for
+// that package.
+func (eh *goTestEventHandler) testResults() map[string][]*testCaseResult {
+	r := map[string][]*testCaseResult{}
+	for pkg, pt := range eh.packageTests {
+		if len(pt.Tests) > 0 {
+			r[pkg] = pt.Tests
+		}
+	}
+	return r
+}
@@ -0,0 +1,155 @@
+package gocli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/leep-frog/command/command"
+	"github.com/leep-frog/command/commandtest"
+)
+
+func TestPackageTests(t *testing.T) {
+	pt := &packageTests{byName: map[string]*testCaseResult{}}
+	for _, name := range []string{
+		"TestA",
+		"TestA/one",
+		"TestB",
+		"TestA/one/nested",
+		"TestA/two/with/slashes",
+		"TestA/two/with/slashes/nested",
+		"TestA/one",
+	} {
+		pt.get(name)
+	}
+
+	want := []*testCaseResult{
+		{
+			Name: "TestA",
+			Subtests: []*testCaseResult{
+				{
+					Name: "TestA/one",
+					Subtests: []*testCaseResult{
+						{Name: "TestA/one/nested"},
+					},
+				},
+				{
+					Name: "TestA/two/with/slashes",
+					Subtests: []*testCaseResult{
+						{Name: "TestA/two/with/slashes/nested"},
+					},
+				},
+			},
+		},
+		{Name: "TestB"},
+	}
+	if diff := cmp.Diff(want, pt.Tests); diff != "" {
+		t.Errorf("packageTests produced incorrect hierarchy (-want, +got):\n%s", diff)
+	}
+	if got, want := len(pt.all), 6; got != want {
+		t.Errorf("packageTests contains %d tests; want %d", got, want)
+	}
+}
+
+func TestEventHandler(t *testing.T) {
+	contents, err := os.ReadFile(filepath.Join("testdata", "out.json"))
+	if err != nil {
+		t.Fatalf("failed to read test events: %v", err)
+	}
+
+	for _, test := range []struct {
+		name      string
+		chunkSize int
+		verbose   bool
+	}{
+		{
+			name:      "handles events written line by line",
+			chunkSize: len(contents),
+		},
+		{
+			name:      "handles events split across writes",
+			chunkSize: 7,
+		},
+		{
+			name:      "handles events in verbose mode",
+			chunkSize: 100,
+			verbose:   true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := newGoTestEventHandler(test.verbose, false)
+			o := commandtest.NewOutput()
+			d := &command.Data{}
+			for b := contents; len(b) > 0; {
+				n := test.chunkSize
+				if n > len(b) {
+					n = len(b)
+				}
+				if err := eh.streamFunc(o, d, b[:n]); err != nil {
+					t.Fatalf("streamFunc returned error: %v", err)
+				}
+				b = b[n:]
+			}
+			eh.flush(o)
+
+			if eh.err != nil {
+				t.Fatalf("event handler returned error: %v", eh.err)
+			}
+
+			pkg := "github.com/leep-frog/gocli"
+			wantResults := map[string]*packageResult{
+				pkg: {
+					TestResult: testFailure,
+					Line:       "FAIL\tgithub.com/leep-frog/gocli\t0.064s",
+					Elapsed:    0.064,
+				},
+			}
+			if diff := cmp.Diff(wantResults, eh.packageResults); diff != "" {
+				t.Errorf("goTestEventHandler produced incorrect package results (-want, +got):\n%s", diff)
+			}
+
+			wantTests := map[string][]*testCaseResult{
+				pkg: {{
+					Name: "TestExecute",
+					Subtests: []*testCaseResult{
+						{Name: "TestExecute/Fails_if_shell_command_fails"},
+						{Name: "TestExecute/Fails_if_unknown_action_for_package"},
+						{Name: "TestExecute/Fails_if_unknown_action_for_test"},
+						{Name: "TestExecute/Fails_if_invalid_json"},
+						{Name: "TestExecute/Ignore_actions_action"},
+					},
+				}},
+			}
+			if diff := cmp.Diff(wantTests, eh.testResults(), cmpopts.IgnoreFields(testCaseResult{}, "Output")); diff != "" {
+				t.Errorf("goTestEventHandler produced incorrect test results (-want, +got):\n%s", diff)
+			}
+
+			wantOutput := []string{
+				"=== RUN   TestExecute/Ignore_actions_action",
+				"panic: runtime error: invalid memory address or nil pointer dereference",
+				"[signal 0xc0000005 code=0x0 addr=0x20 pc=0x2a9ffb]",
+				"",
+				"goroutine 51 [running]:",
+			}
+			if diff := cmp.Diff(wantOutput, eh.packageTests[pkg].byName["TestExecute/Ignore_actions_action"].Output[:len(wantOutput)]); diff != "" {
+				t.Errorf("goTestEventHandler produced incorrect test output (-want, +got):\n%s", diff)
+			}
+
+			stdout := o.GetStdout()
+			if got := strings.Contains(stdout, "=== RUN"); got != test.verbose {
+				t.Errorf("stdout contains test framing lines: %v; want %v", got, test.verbose)
+			}
+			for _, want := range []string{
+				"panic: runtime error: invalid memory address or nil pointer dereference\n",
+				"FAIL\tgithub.com/leep-frog/gocli\t0.064s\n",
+			} {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("stdout does not contain %q:\n%s", want, stdout)
+				}
+			}
+		})
+	}
+}
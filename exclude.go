@@ -0,0 +1,145 @@
+package gocli
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"strings"
+)
+
+// noCoverMarker is the comment that excludes the function or block that
+// starts on the same line as (or the line after) the comment from coverage.
+const noCoverMarker = "//gocli:nocover"
+
+// sourceRange is a range of positions in a source file.
+type sourceRange struct {
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+}
+
+// contains returns whether or not the block is entirely inside of the range.
+func (sr *sourceRange) contains(b *coverBlock) bool {
+	return !positionBefore(b.StartLine, b.StartCol, sr.StartLine, sr.StartCol) && !positionBefore(sr.EndLine, sr.EndCol, b.EndLine, b.EndCol)
+}
+
+// validateExcludePatterns returns an error if any of the patterns are invalid.
+func validateExcludePatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %v", p, err)
+		}
+	}
+	return nil
+}
+
+// excludedFile returns whether or not the file matches any of the exclude
+// patterns. Patterns are matched against the file's path relative to the
+// module root and against the file's base name (e.g. `*.pb.go`).
+func excludedFile(name string, mod *goModule, patterns []string) bool {
+	rel := mod.relativePath(name)
+	for _, p := range patterns {
+		if m, _ := path.Match(p, rel); m {
+			return true
+		}
+		if m, _ := path.Match(p, path.Base(name)); m {
+			return true
+		}
+	}
+	return false
+}
+
+// noCoverRanges returns the ranges of the file that are excluded from coverage
+// by noCoverMarker comments. Files that can't be parsed are ignored.
+func noCoverRanges(filename string) []*sourceRange {
+	if filename == "" {
+		return nil
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil || !strings.Contains(string(b), noCoverMarker) {
+		return nil
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, b, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+
+	// markers contains every line with a marker, and lineMarkers only contains
+	// lines where the marker is the only thing on the line (a trailing marker
+	// only applies to its own line, not the following one).
+	markers, lineMarkers := map[int]bool{}, map[int]bool{}
+	lines := strings.Split(string(b), "\n")
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			if !strings.HasPrefix(c.Text, noCoverMarker) {
+				continue
+			}
+			pos := fset.Position(c.Pos())
+			markers[pos.Line] = true
+			if strings.TrimSpace(lines[pos.Line-1][:pos.Column-1]) == "" {
+				lineMarkers[pos.Line] = true
+			}
+		}
+	}
+
+	// The outermost function or block that starts on a marked line is excluded.
+	var ranges []*sourceRange
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FuncDecl, *ast.FuncLit, *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+		default:
+			return true
+		}
+		start, end := fset.Position(n.Pos()), fset.Position(n.End())
+		if !markers[start.Line] && !lineMarkers[start.Line-1] {
+			return true
+		}
+		ranges = append(ranges, &sourceRange{start.Line, start.Column, end.Line, end.Column})
+		return false
+	})
+	return ranges
+}
+
+// excludeCoverage returns a copy of the profile without the blocks in files
+// that match the exclude patterns or in code marked with noCoverMarker, along
+// with the number of statements that were excluded.
+func excludeCoverage(cp *coverProfile, mod *goModule, patterns []string) (*coverProfile, int) {
+	r := &coverProfile{
+		Mode:  cp.Mode,
+		Files: map[string][]*coverBlock{},
+	}
+	var excluded int
+	for name, blocks := range cp.Files {
+		if excludedFile(name, mod, patterns) {
+			for _, b := range blocks {
+				excluded += b.NumStmt
+			}
+			continue
+		}
+
+		ranges := noCoverRanges(mod.sourcePath(name))
+		for _, b := range blocks {
+			if inRanges(ranges, b) {
+				excluded += b.NumStmt
+				continue
+			}
+			r.Files[name] = append(r.Files[name], b)
+		}
+	}
+	return r, excluded
+}
+
+func inRanges(ranges []*sourceRange, b *coverBlock) bool {
+	for _, sr := range ranges {
+		if sr.contains(b) {
+			return true
+		}
+	}
+	return false
+}
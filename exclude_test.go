@@ -0,0 +1,112 @@
+package gocli
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidateExcludePatterns(t *testing.T) {
+	if err := validateExcludePatterns([]string{"*.pb.go", "internal/gen/*"}); err != nil {
+		t.Errorf("validateExcludePatterns() returned error: %v", err)
+	}
+	want := fmt.Errorf(`invalid exclude pattern "[": syntax error in pattern`)
+	if diff := cmp.Diff(want, validateExcludePatterns([]string{"*.go", "["}), cmpErrors()); diff != "" {
+		t.Errorf("validateExcludePatterns() returned incorrect error (-want, +got):\n%s", diff)
+	}
+}
+
+func TestExcludeCoverage(t *testing.T) {
+	mod := testModule(t)
+	nocoverFile := testModulePath + "/testdata/nocover/nocover.go"
+	cp := &coverProfile{
+		Mode: "set",
+		Files: map[string][]*coverBlock{
+			nocoverFile: {
+				{StartLine: 3, StartCol: 29, EndLine: 4, EndCol: 16, NumStmt: 1, Count: 1},
+				{StartLine: 4, StartCol: 16, EndLine: 6, EndCol: 3, NumStmt: 1, Count: 0},
+				{StartLine: 7, StartCol: 2, EndLine: 7, EndCol: 12, NumStmt: 1, Count: 1},
+				{StartLine: 11, StartCol: 20, EndLine: 13, EndCol: 2, NumStmt: 1, Count: 0},
+				{StartLine: 15, StartCol: 24, EndLine: 16, EndCol: 11, NumStmt: 1, Count: 1},
+				{StartLine: 17, StartCol: 9, EndLine: 18, EndCol: 11, NumStmt: 1, Count: 1},
+				{StartLine: 20, StartCol: 9, EndLine: 21, EndCol: 11, NumStmt: 1, Count: 0},
+				{StartLine: 23, StartCol: 2, EndLine: 23, EndCol: 10, NumStmt: 1, Count: 1},
+				{StartLine: 26, StartCol: 27, EndLine: 28, EndCol: 7, NumStmt: 2, Count: 1},
+				{StartLine: 28, StartCol: 7, EndLine: 30, EndCol: 3, NumStmt: 1, Count: 0},
+				{StartLine: 31, StartCol: 2, EndLine: 31, EndCol: 10, NumStmt: 1, Count: 1},
+			},
+			testModulePath + "/api/api.pb.go": {
+				{StartLine: 1, StartCol: 1, EndLine: 3, EndCol: 2, NumStmt: 3, Count: 0},
+			},
+			testModulePath + "/internal/gen/zz_generated.deepcopy.go": {
+				{StartLine: 1, StartCol: 1, EndLine: 3, EndCol: 2, NumStmt: 2, Count: 0},
+			},
+			testModulePath + "/internal/gen/types.go": {
+				{StartLine: 1, StartCol: 1, EndLine: 3, EndCol: 2, NumStmt: 1, Count: 0},
+			},
+			testCoverFile: {
+				{StartLine: 3, StartCol: 26, EndLine: 4, EndCol: 7, NumStmt: 1, Count: 1},
+			},
+		},
+	}
+
+	for _, test := range []struct {
+		name         string
+		patterns     []string
+		wantExcluded int
+	}{
+		{
+			name:         "excludes nocover markers",
+			wantExcluded: 3,
+		},
+		{
+			name:         "excludes files matching base name patterns",
+			patterns:     []string{"*.pb.go", "zz_generated*.go"},
+			wantExcluded: 8,
+		},
+		{
+			name:         "excludes files matching relative path patterns",
+			patterns:     []string{"internal/gen/*"},
+			wantExcluded: 6,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, excluded := excludeCoverage(cp, mod, test.patterns)
+			if excluded != test.wantExcluded {
+				t.Errorf("excludeCoverage(%v) excluded %d statements; want %d", test.patterns, excluded, test.wantExcluded)
+			}
+
+			var wantStmts int
+			for _, blocks := range cp.Files {
+				for _, b := range blocks {
+					wantStmts += b.NumStmt
+				}
+			}
+			var gotStmts int
+			for _, blocks := range got.Files {
+				for _, b := range blocks {
+					gotStmts += b.NumStmt
+				}
+			}
+			if gotStmts+excluded != wantStmts {
+				t.Errorf("excludeCoverage(%v) kept %d statements and excluded %d; want %d total", test.patterns, gotStmts, excluded, wantStmts)
+			}
+
+			wantNoCover := []*coverBlock{
+				cp.Files[nocoverFile][0],
+				cp.Files[nocoverFile][2],
+				cp.Files[nocoverFile][4],
+				cp.Files[nocoverFile][5],
+				cp.Files[nocoverFile][7],
+				// A trailing marker doesn't exclude the block on the next line.
+				cp.Files[nocoverFile][8],
+				cp.Files[nocoverFile][9],
+				cp.Files[nocoverFile][10],
+			}
+			if diff := cmp.Diff(wantNoCover, got.Files[nocoverFile]); diff != "" {
+				t.Errorf("excludeCoverage(%v) returned incorrect blocks (-want, +got):\n%s", test.patterns, diff)
+			}
+		})
+	}
+}
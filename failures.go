@@ -0,0 +1,192 @@
+package gocli
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+var (
+	// failedPackageRegex matches the line printed by `go test` when a package
+	// can't be built or set up (older versions of go print this as plain text
+	// even when run with `-json`).
+	failedPackageRegex = regexp.MustCompile(`^FAIL\s+(\S+)\s+\[(build|setup) failed\]$`)
+	timeoutRegex       = regexp.MustCompile(`^panic: test timed out after (\S+)`)
+	runningTestRegex   = regexp.MustCompile(`^\s+(Test\S*|Fuzz\S*|Example\S*) \(\S+\)$`)
+	stackFrameRegex    = regexp.MustCompile(`^\s+(\S+\.go):([0-9]+)(?: \+0x[0-9a-f]+)?$`)
+)
+
+// failureMessage returns the error message for a package that didn't succeed
+// (or an empty string if the package succeeded).
+func (pr *packageResult) failureMessage(pkg string) string {
+	var msg string
+	switch pr.TestResult {
+	case testFailure:
+		return fmt.Sprintf("Tests failed for package: %s", pkg)
+	case buildFailure:
+		msg = fmt.Sprintf("Build failed for package: %s", pkg)
+	case setupFailure:
+		msg = fmt.Sprintf("Setup failed for package: %s", pkg)
+	case testPanic:
+		msg = fmt.Sprintf("Test panicked in package %s: %s", pkg, pr.FailedTest)
+	case testTimeout:
+		msg = fmt.Sprintf("Test timed out in package %s: %s", pkg, pr.FailedTest)
+	default:
+		return ""
+	}
+	if pr.FailureDetail != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, pr.FailureDetail)
+	}
+	return msg
+}
+
+// classifyFailures determines why each failed package failed (e.g. a build
+// failure or a panic) and updates the package results accordingly.
+func (eh *goTestEventHandler) classifyFailures(mod *goModule) {
+	for pkg, pr := range eh.packageResults {
+		if pr.TestResult == testFailure {
+			eh.classifyFailure(pkg, pr, mod)
+		}
+	}
+}
+
+func (eh *goTestEventHandler) classifyFailure(pkg string, pr *packageResult, mod *goModule) {
+	if m := failedPackageRegex.FindStringSubmatch(pr.Line); m != nil || eh.failedBuilds[pkg] != "" {
+		if m != nil && m[2] == "setup" {
+			pr.TestResult = setupFailure
+		} else {
+			pr.TestResult = buildFailure
+		}
+		buildOutput := eh.buildOutput[eh.failedBuilds[pkg]]
+		if len(buildOutput) == 0 {
+			buildOutput = eh.packageOutput[pkg]
+		}
+		pr.FailureDetail = firstBuildError(buildOutput)
+		return
+	}
+
+	pt := eh.packageTestsFor(pkg)
+	outputs := [][]string{eh.packageOutput[pkg]}
+	for _, tcr := range pt.all {
+		outputs = append(outputs, tcr.Output)
+	}
+
+	// Check for timeouts
+	for _, lines := range outputs {
+		for i, line := range lines {
+			m := timeoutRegex.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			pr.TestResult = testTimeout
+			pr.FailureDetail = fmt.Sprintf("after %s", m[1])
+			if frame := firstUserFrame(lines[i+1:], mod); frame != "" {
+				pr.FailureDetail = fmt.Sprintf("%s at %s", pr.FailureDetail, frame)
+			}
+			for _, running := range lines[i+1:] {
+				if rm := runningTestRegex.FindStringSubmatch(running); rm != nil {
+					pr.FailedTest = rm[1]
+					break
+				}
+			}
+			return
+		}
+	}
+
+	// Check for panics
+	for i, lines := range outputs {
+		for j, line := range lines {
+			if !strings.HasPrefix(line, "panic: ") {
+				continue
+			}
+			pr.TestResult = testPanic
+			pr.FailureDetail = firstUserFrame(lines[j+1:], mod)
+			if i > 0 {
+				pr.FailedTest = panickingTest(pt.all[i-1]).Name
+			} else {
+				// The panic wasn't attributed to a test, so assume it came from
+				// the first test that never finished.
+				for _, tcr := range pt.all {
+					if tcr.Status == testRunning {
+						pr.FailedTest = tcr.Name
+						break
+					}
+				}
+			}
+			return
+		}
+	}
+}
+
+// panickingTest returns the test that most likely caused a panic whose output
+// was attributed to the provided test. When a subtest panics, the testing
+// package marks the subtest (and all of its parents) as failed, but the panic
+// output itself may be attributed to an ancestor.
+func panickingTest(tcr *testCaseResult) *testCaseResult {
+	for {
+		var failed *testCaseResult
+		for _, sub := range tcr.Subtests {
+			if isUnsuccessful(sub) {
+				failed = sub
+			}
+		}
+		if failed == nil {
+			return tcr
+		}
+		tcr = failed
+	}
+}
+
+// firstBuildError returns the first compiler error from build output.
+func firstBuildError(lines []string) string {
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || failedPackageRegex.MatchString(trimmed) || trimmed == "FAIL" {
+			continue
+		}
+		return trimmed
+	}
+	return ""
+}
+
+// firstUserFrame returns the location (`file:line`) of the first stack frame
+// that is in the user's code (as opposed to the go runtime or a dependency).
+func firstUserFrame(stack []string, mod *goModule) string {
+	for _, line := range stack {
+		m := stackFrameRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if loc, ok := userFrameLocation(m[1], mod); ok {
+			return fmt.Sprintf("%s:%s", loc, m[2])
+		}
+	}
+	return ""
+}
+
+// userFrameLocation returns the display path of a stack frame's file and
+// whether or not the file is part of the user's code.
+func userFrameLocation(file string, mod *goModule) (string, bool) {
+	path := filepath.FromSlash(file)
+	// Relative paths (e.g. `_testmain.go`) are generated by go itself.
+	if !filepath.IsAbs(path) {
+		return "", false
+	}
+	if mod != nil {
+		rel, err := filepath.Rel(mod.Dir, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return "", false
+		}
+		return filepath.ToSlash(rel), true
+	}
+
+	if goroot := runtime.GOROOT(); goroot != "" && strings.HasPrefix(path, filepath.Clean(goroot)+string(filepath.Separator)) {
+		return "", false
+	}
+	if strings.Contains(filepath.ToSlash(path), "/pkg/mod/") {
+		return "", false
+	}
+	return file, true
+}
@@ -0,0 +1,162 @@
+package gocli
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/leep-frog/command/commandtest"
+)
+
+func TestClassifyFailures(t *testing.T) {
+	mod := testModule(t)
+	userFile := filepath.ToSlash(filepath.Join(mod.Dir, "p1", "p1_test.go"))
+	goFile := filepath.ToSlash(filepath.Join(runtime.GOROOT(), "src", "testing", "testing.go"))
+
+	for _, test := range []struct {
+		name   string
+		mod    *goModule
+		events []*testEvent
+		want   *packageResult
+	}{
+		{
+			name: "leaves regular failures alone",
+			mod:  mod,
+			events: []*testEvent{
+				{Action: actionOutput, Package: "p1", Test: "TestOne", Output: "    p1_test.go:12: oops\n"},
+				{Action: actionFail, Package: "p1", Test: "TestOne"},
+				{Action: actionOutput, Package: "p1", Output: "FAIL\n"},
+				{Action: actionFail, Package: "p1"},
+			},
+			want: &packageResult{TestResult: testFailure, Line: "FAIL"},
+		},
+		{
+			name: "identifies panicking subtest and user frame",
+			mod:  mod,
+			events: []*testEvent{
+				{Action: "run", Package: "p1", Test: "TestOne"},
+				{Action: actionOutput, Package: "p1", Test: "TestOne/sub", Output: "--- FAIL: TestOne/sub (0.00s)\n"},
+				{Action: actionFail, Package: "p1", Test: "TestOne/sub"},
+				{Action: actionOutput, Package: "p1", Test: "TestOne", Output: "--- FAIL: TestOne (0.00s)\n"},
+				{Action: actionOutput, Package: "p1", Test: "TestOne", Output: "panic: assignment to entry in nil map [recovered]\n"},
+				{Action: actionOutput, Package: "p1", Test: "TestOne", Output: "testing.tRunner.func1()\n"},
+				{Action: actionOutput, Package: "p1", Test: "TestOne", Output: "\t" + goFile + ":2126 +0x329\n"},
+				{Action: actionOutput, Package: "p1", Test: "TestOne", Output: "example.com/p1.helper(...)\n"},
+				{Action: actionOutput, Package: "p1", Test: "TestOne", Output: "\t" + userFile + ":3\n"},
+				{Action: actionFail, Package: "p1", Test: "TestOne"},
+				{Action: actionFail, Package: "p1"},
+			},
+			want: &packageResult{
+				TestResult:    testPanic,
+				FailedTest:    "TestOne/sub",
+				FailureDetail: "p1/p1_test.go:3",
+			},
+		},
+		{
+			name: "identifies panic without module",
+			events: []*testEvent{
+				{Action: actionOutput, Package: "p1", Test: "TestOne", Output: "=== RUN   TestOne\n"},
+				{Action: actionOutput, Package: "p1", Output: "panic: oops\n"},
+				{Action: actionOutput, Package: "p1", Output: "\t" + goFile + ":2126 +0x329\n"},
+				{Action: actionOutput, Package: "p1", Output: "\t/home/me/go/pkg/mod/example.com/dep/dep.go:7 +0x12\n"},
+				{Action: actionOutput, Package: "p1", Output: "\t_testmain.go:46 +0x9b\n"},
+				{Action: actionOutput, Package: "p1", Output: "\t" + userFile + ":3 +0x18\n"},
+				{Action: actionFail, Package: "p1"},
+			},
+			want: &packageResult{
+				TestResult:    testPanic,
+				FailedTest:    "TestOne",
+				FailureDetail: userFile + ":3",
+				Line:          "\t" + userFile + ":3 +0x18",
+			},
+		},
+		{
+			name: "identifies timeout",
+			mod:  mod,
+			events: []*testEvent{
+				{Action: actionOutput, Package: "p1", Test: "TestSlow", Output: "panic: test timed out after 2m0s\n"},
+				{Action: actionOutput, Package: "p1", Test: "TestSlow", Output: "\trunning tests:\n"},
+				{Action: actionOutput, Package: "p1", Test: "TestSlow", Output: "\t\tTestSlow/sub (2m0s)\n"},
+				{Action: actionOutput, Package: "p1", Test: "TestSlow", Output: "\t" + goFile + ":2959 +0x34a\n"},
+				{Action: actionOutput, Package: "p1", Test: "TestSlow", Output: "\t" + userFile + ":8 +0x18\n"},
+				{Action: actionFail, Package: "p1"},
+			},
+			want: &packageResult{
+				TestResult:    testTimeout,
+				FailedTest:    "TestSlow/sub",
+				FailureDetail: "after 2m0s at p1/p1_test.go:8",
+			},
+		},
+		{
+			name: "identifies build failure",
+			mod:  mod,
+			events: []*testEvent{
+				{Action: actionBuildOutput, ImportPath: "p1 [p1.test]", Output: "# p1 [p1.test]\n"},
+				{Action: actionBuildOutput, ImportPath: "p1 [p1.test]", Output: "p1/p1.go:1:1: expected 'package', found 'EOF'\n"},
+				{Action: actionFail, Package: "p1", FailedBuild: "p1 [p1.test]"},
+			},
+			want: &packageResult{
+				TestResult:    buildFailure,
+				FailureDetail: "p1/p1.go:1:1: expected 'package', found 'EOF'",
+			},
+		},
+		{
+			name: "identifies setup failure",
+			mod:  mod,
+			events: []*testEvent{
+				{Action: actionOutput, Package: "p1", Output: "# p1\n"},
+				{Action: actionOutput, Package: "p1", Output: "package p1: invalid import path\n"},
+				{Action: actionOutput, Package: "p1", Output: "FAIL\tp1 [setup failed]\n"},
+				{Action: actionFail, Package: "p1"},
+			},
+			want: &packageResult{
+				TestResult:    setupFailure,
+				FailureDetail: "package p1: invalid import path",
+				Line:          "FAIL\tp1 [setup failed]",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := newGoTestEventHandler(false, true)
+			o := commandtest.NewOutput()
+			for _, e := range test.events {
+				if err := eh.processEvent(o, e); err != nil {
+					t.Fatalf("processEvent(%v) returned error: %v", e, err)
+				}
+			}
+			eh.classifyFailures(test.mod)
+
+			if diff := cmp.Diff(test.want, eh.packageResults["p1"]); diff != "" {
+				t.Errorf("classifyFailures() produced incorrect result (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFailureMessage(t *testing.T) {
+	for _, test := range []struct {
+		pr   *packageResult
+		want string
+	}{
+		{
+			pr: &packageResult{TestResult: testSuccess},
+		},
+		{
+			pr:   &packageResult{TestResult: testFailure, FailureDetail: "ignored"},
+			want: "Tests failed for package: p1",
+		},
+		{
+			pr:   &packageResult{TestResult: buildFailure},
+			want: "Build failed for package: p1",
+		},
+		{
+			pr:   &packageResult{TestResult: testPanic, FailedTest: "TestOne", FailureDetail: "p1/p1_test.go:3"},
+			want: "Test panicked in package p1: TestOne (p1/p1_test.go:3)",
+		},
+	} {
+		if got := test.pr.failureMessage("p1"); got != test.want {
+			t.Errorf("failureMessage(%v) returned %q; want %q", test.pr, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package gocli
+
+import (
+	"fmt"
+
+	"github.com/leep-frog/command/command"
+)
+
+// funcListing is the coverage of a single function along with its location.
+type funcListing struct {
+	// Location is the file (relative to the module) and line of the function.
+	Location string
+	Func     *funcCoverage
+}
+
+// funcListings returns the coverage of every function in the report, sorted
+// by file and line.
+func funcListings(report *coverageReport, mod *goModule) []*funcListing {
+	var r []*funcListing
+	for _, fc := range sortedFiles(report) {
+		for _, f := range fc.Funcs {
+			r = append(r, &funcListing{
+				Location: fmt.Sprintf("%s:%d", mod.relativePath(fc.Name), f.StartLine),
+				Func:     f,
+			})
+		}
+	}
+	return r
+}
+
+// printFuncCoverage prints the coverage of every function.
+func printFuncCoverage(o command.Output, funcs []*funcListing) {
+	if len(funcs) == 0 {
+		return
+	}
+
+	var locWidth, nameWidth int
+	for _, fl := range funcs {
+		locWidth = max(locWidth, len(fl.Location))
+		nameWidth = max(nameWidth, len(fl.Func.Name))
+	}
+	o.Stdoutln("Function coverage:")
+	for _, fl := range funcs {
+		o.Stdoutf("  %-*s  %-*s  %6s\n", locWidth, fl.Location, nameWidth, fl.Func.Name, percentFormat(fl.Func.Counts.Percent()))
+	}
+}
+
+// checkExportedFuncs returns an error listing every exported function that
+// has statements, but no coverage.
+func checkExportedFuncs(o command.Output, funcs []*funcListing) error {
+	var untested []*funcListing
+	for _, fl := range funcs {
+		if fl.Func.Exported && fl.Func.Counts.Statements > 0 && fl.Func.Counts.Covered == 0 {
+			untested = append(untested, fl)
+		}
+	}
+	if len(untested) == 0 {
+		return nil
+	}
+
+	o.Stderrln("Exported functions without coverage:")
+	for _, fl := range untested {
+		o.Stderrf("  %s %s\n", fl.Location, fl.Func.Name)
+	}
+	return o.Stderrf("%d exported function(s) have no coverage\n", len(untested))
+}
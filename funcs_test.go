@@ -0,0 +1,70 @@
+package gocli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/leep-frog/command/commandtest"
+)
+
+func TestPrintFuncCoverage(t *testing.T) {
+	report, mod := exportTestReport(t)
+	o := commandtest.NewOutput()
+	printFuncCoverage(o, funcListings(report, mod))
+	o.Close()
+
+	want := strings.Join([]string{
+		"Function coverage:",
+		"  testdata/cover/cover.go:3   Covered           66.7%",
+		"  testdata/cover/cover.go:12  (*thing).method    0.0%",
+		"",
+	}, "\n")
+	if diff := cmp.Diff(want, o.GetStdout()); diff != "" {
+		t.Errorf("printFuncCoverage() printed incorrect output (-want, +got):\n%s", diff)
+	}
+}
+
+func TestCheckExportedFuncs(t *testing.T) {
+	funcs := []*funcListing{
+		{"a.go:1", &funcCoverage{Name: "Covered", Exported: true, Counts: coverageCounts{Statements: 2, Covered: 1}}},
+		{"a.go:5", &funcCoverage{Name: "Untested", Exported: true, Counts: coverageCounts{Statements: 2}}},
+		{"a.go:9", &funcCoverage{Name: "unexported", Counts: coverageCounts{Statements: 2}}},
+		{"a.go:12", &funcCoverage{Name: "Empty", Exported: true}},
+		{"b.go:3", &funcCoverage{Name: "(*T).Method", Exported: true, Counts: coverageCounts{Statements: 1}}},
+	}
+
+	for _, test := range []struct {
+		name       string
+		funcs      []*funcListing
+		wantStderr string
+	}{
+		{
+			name:  "passes if all exported functions are covered",
+			funcs: funcs[:1],
+		},
+		{
+			name:  "fails if exported functions are untested",
+			funcs: funcs,
+			wantStderr: strings.Join([]string{
+				"Exported functions without coverage:",
+				"  a.go:5 Untested",
+				"  b.go:3 (*T).Method",
+				"2 exported function(s) have no coverage",
+				"",
+			}, "\n"),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			o := commandtest.NewOutput()
+			err := checkExportedFuncs(o, test.funcs)
+			o.Close()
+			if gotErr := err != nil; gotErr != (test.wantStderr != "") {
+				t.Errorf("checkExportedFuncs() returned error %v; want error: %v", err, test.wantStderr != "")
+			}
+			if diff := cmp.Diff(test.wantStderr, o.GetStderr()); diff != "" {
+				t.Errorf("checkExportedFuncs() printed incorrect stderr (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
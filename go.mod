@@ -3,9 +3,12 @@ module github.com/leep-frog/gocli
 go 1.21.0
 
 require (
-	github.com/google/go-cmp v0.5.8 // indirect
+	github.com/google/go-cmp v0.5.8
+	github.com/leep-frog/command v0.0.0-20241113025355-7db1f0f70873
+	golang.org/x/exp v0.0.0-20231006140011-7918f672742d
+)
+
+require (
 	github.com/google/uuid v1.4.0 // indirect
-	github.com/leep-frog/command v0.0.0-20241113025355-7db1f0f70873 // indirect
-	golang.org/x/exp v0.0.0-20231006140011-7918f672742d // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 )
@@ -1,14 +1,9 @@
 package gocli
 
 import (
-	"bufio"
 	"fmt"
-	"io/fs"
 	"io/ioutil"
 	"os"
-	"path/filepath"
-	"regexp"
-	"strconv"
 	"strings"
 
 	"github.com/leep-frog/command/command"
@@ -35,70 +30,75 @@ func (gc *goCLI) Setup() []string { return nil }
 func (gc *goCLI) Name() string    { return "gt" }
 
 var (
-	coverageRegex = regexp.MustCompile(`^ok\s+([^\s]+)\s+[0-9\.a-zA-Z]+\s+coverage:\s+([0-9]+\.[0-9]+)% of statements` + "\n?$")
-	noTestRegex   = regexp.MustCompile(`^\?\s+([^\s]+)\s+\[no test files\]` + "\n?$")
-	testFailRegex = regexp.MustCompile(`^FAIL\s+([^\s]+)\s+`)
-
-	findTestRegex = regexp.MustCompile(`^func\s+Test([a-zA-Z0-9_]*)\b.*\*testing\.[A-Z]\b`)
-	testFileRegex = regexp.MustCompile(`.*_test.go$`)
-
 	// Args and flags
-	pathArgs         = commander.ListArg[string]("PATH", "Path(s) to go packages to test", 0, command.UnboundedList, &commander.FileCompleter[[]string]{Distinct: true, IgnoreFiles: true}, commander.Default([]string{"."}))
+	pathArgs         = commander.ListArg[string]("PATH", "Path(s) to go packages to test", 0, command.UnboundedList, &commander.FileCompleter[[]string]{Distinct: true, IgnoreFiles: true})
 	verboseFlag      = commander.BoolFlag("verbose", 'v', "Whether or not to test with verbose output")
-	minCoverageFlag  = commander.Flag[float64]("minCoverage", 'm', "If set, enforces that minimum coverage is met", commander.Positive[float64](), commander.LTE[float64](100), commander.Default[float64](0))
+	quietFlag        = commander.BoolFlag("quiet", 'q', "If set, test output isn't streamed and only the failure summary is printed")
+	minCoverageFlag  = commander.Flag[float64]("minCoverage", 'm', "If set, enforces that minimum coverage is met", commander.Positive[float64](), commander.LTE[float64](100))
 	packageCountFlag = commander.Flag[int]("package-count", 'p', "Number of packages to expect output for")
+	coverageRuleFlag = commander.ListFlag[string]("package-coverage", 'c', "Minimum coverage for packages matching a pattern, formatted as PATTERN=PERCENT (e.g. ./internal/...=80). The most specific pattern takes precedence over the minCoverage flag", 0, command.UnboundedList)
 	timeoutFlag      = commander.Flag[int]("timeout", 't', "Test timeout in seconds", commander.Positive[int]())
+	excludeFlag      = commander.ListFlag[string]("exclude", 'e', "Glob patterns of files to exclude from coverage (e.g. *.pb.go). Patterns are matched against the file's base name and its path relative to the module root", 0, command.UnboundedList)
+	diffBaseFlag     = commander.Flag[string]("diff-base", 'd', "If set, minimum coverage is only enforced on lines changed since this git ref (instead of per package)")
+	uncoveredFlag    = commander.BoolFlag("uncovered", 'u', "If set, the uncovered lines of every file are printed")
+	funcsFlag        = commander.BoolFlag("funcs", commander.FlagNoShortName, "If set, the coverage of every function is printed")
+	requireExpFlag   = commander.BoolFlag("require-exported", commander.FlagNoShortName, "If set, fails if any exported function has no coverage")
+	noRequireExpFlag = commander.BoolFlag("no-require-exported", commander.FlagNoShortName, "If set, ignores the requireExported value from the config file")
+	snippetsFlag     = commander.BoolFlag("snippets", 's', "If set, source code is included for uncovered lines (requires --uncovered)")
+	htmlFlag         = commander.Flag[string]("html", commander.FlagNoShortName, "If set, an HTML coverage report is written to this directory", &commander.FileCompleter[string]{IgnoreFiles: true})
+	badgeFlag        = commander.Flag[string]("badge", commander.FlagNoShortName, "If set, an SVG badge with the total coverage is written to this file", &commander.FileCompleter[string]{})
+	lcovFlag         = commander.Flag[string]("lcov", commander.FlagNoShortName, "If set, the coverage profile is written to this file in LCOV format", &commander.FileCompleter[string]{})
+	coberturaFlag    = commander.Flag[string]("cobertura", commander.FlagNoShortName, "If set, the coverage profile is written to this file in Cobertura XML format", &commander.FileCompleter[string]{})
+	crossPackageFlag = commander.BoolFlag("cross-package", 'x', "If set, coverage is measured across all packages in PATH (via go test's -coverpkg flag), so code tested from other packages counts toward a package's coverage")
+	coverPkgFlag     = commander.Flag[string]("coverpkg", commander.FlagNoShortName, "If set, coverage is measured across the packages matching this comma-separated list of patterns (passed to go test's -coverpkg flag)")
+	coverProfileFlag = commander.Flag[string]("coverprofile", commander.FlagNoShortName, "If set, the coverage profile is kept at this path (otherwise, a temporary file is used and removed)", &commander.FileCompleter[string]{})
+	noRegressFlag    = commander.BoolFlag("no-regress", 'r', "If set, fails if the coverage of any package dropped from the coverage recorded for the most recent other commit")
+	regressTolFlag   = commander.Flag[float64]("regress-tolerance", commander.FlagNoShortName, "Percentage points that package coverage may drop before --no-regress fails", commander.NonNegative[float64](), commander.LTE[float64](100))
+	junitFlag        = commander.Flag[string]("junit", 'j', "If set, a JUnit XML report is written to this file", &commander.FileCompleter[string]{})
+	jsonOutFlag      = commander.Flag[string]("json-out", commander.FlagNoShortName, "If set, a JSON summary of the results is written to this file", &commander.FileCompleter[string]{})
+
+	failedFlag = commander.BoolFlag("failed", commander.FlagNoShortName, "If set, only the tests that failed in the previous run are rerun (coverage isn't checked)")
+
+	funcFilterFlag = commander.ListFlag[string]("func-filter", 'f', "The test function filter", 0, command.UnboundedList, testFuncCompleter)
+	skipFlag       = commander.ListFlag[string]("skip", commander.FlagNoShortName, "Test functions to skip (coverage will only include the tests that are run)", 0, command.UnboundedList, testFuncCompleter)
+)
 
-	funcFilterFlag = commander.ListFlag[string]("func-filter", 'f', "The test function filter", 0, command.UnboundedList, commander.DeferredCompleter(commander.SerialNodes(pathArgs), commander.CompleterFromFunc(func(sl []string, data *command.Data) (*command.Completion, error) {
-		suggestions := map[string]bool{}
-		for _, rootPath := range pathArgs.GetOrDefault(data, []string{"."}) {
-			rootOnly := true
-			if rootPath == "./..." {
-				rootOnly = false
-				rootPath = "."
-			}
-
-			if err := filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
-				if err != nil {
-					return err
-				}
-
-				if d.IsDir() {
-					if rootOnly && d.Name() != rootPath {
-						return filepath.SkipDir
-					}
-					return nil
-				}
-
-				if !testFileRegex.MatchString(path) {
-					return nil
-				}
-
-				f, err := os.Open(path)
-				if err != nil {
-					return fmt.Errorf("failed to open test file: %v", err)
-				}
+// testFuncCompleter completes the names (without the `Test` prefix) of the
+// test functions in the provided packages, and the names of their subtests
+// once a test function is selected.
+var testFuncCompleter = commander.DeferredCompleter(commander.SerialNodes(pathArgs), commander.CompleterFromFunc(func(sl []string, data *command.Data) (*command.Completion, error) {
+	var partial string
+	if len(sl) > 0 {
+		partial = sl[len(sl)-1]
+	}
+	pkgs, err := listTestPackages(pathArgs.GetOrDefault(data, []string{"."}))
+	if err != nil {
+		return nil, err
+	}
 
-				for scanner := bufio.NewScanner(f); scanner.Scan(); {
-					m := findTestRegex.FindStringSubmatch(scanner.Text())
-					if len(m) > 0 {
-						suggestions[m[1]] = true
-					}
+	suggestions := map[string]bool{}
+	for _, tp := range pkgs {
+		for _, tf := range tp.Funcs {
+			if tf.Kind != testKindTest {
+				continue
+			}
+			name := strings.TrimPrefix(tf.Name, "Test")
+			// Only suggest subtests once a test function has been selected.
+			if test, _, ok := strings.Cut(partial, "/"); !ok {
+				suggestions[name] = true
+			} else if strings.EqualFold(test, name) {
+				for _, subtest := range tf.Subtests {
+					suggestions[fmt.Sprintf("%s/%s", name, subtest)] = true
 				}
-
-				return nil
-			}); err != nil {
-				return nil, err
 			}
-
 		}
-		return &command.Completion{
-			Suggestions:     maps.Keys(suggestions),
-			Distinct:        true,
-			CaseInsensitive: true,
-		}, nil
-	})))
-)
+	}
+	return &command.Completion{
+		Suggestions:     maps.Keys(suggestions),
+		Distinct:        true,
+		CaseInsensitive: true,
+	}, nil
+}))
 
 func percentFormat(f float64) string {
 	return fmt.Sprintf("%3.1f%%", f)
@@ -110,154 +110,361 @@ const (
 	noTestFiles testResult = iota
 	testSuccess
 	testFailure
+	// buildFailure indicates that the package (or its tests) failed to compile.
+	buildFailure
+	// setupFailure indicates that the package's tests couldn't be set up (e.g.
+	// because of an invalid import path).
+	setupFailure
+	// testPanic indicates that a test panicked.
+	testPanic
+	// testTimeout indicates that the tests didn't complete before the timeout.
+	testTimeout
 )
 
 type packageResult struct {
 	TestResult testResult
-	Coverage   float64
-	Line       string
-}
-
-type goTestEventHandler struct {
-	packageResults map[string]*packageResult
-	err            error
-}
-
-func (eh *goTestEventHandler) setPackageResult(pkg, line string, tr testResult, coverage float64) error {
-	if r, ok := eh.packageResults[pkg]; ok {
-		return fmt.Errorf("Multiple results for package %q:\n  Result 1: %s\n  Result 2: %s", pkg, r.Line, line)
-	}
-	eh.packageResults[pkg] = &packageResult{
-		TestResult: tr,
-		Coverage:   coverage,
-		Line:       line,
-	}
-	return nil
+	// FailedTest is the name of the test that panicked or timed out.
+	FailedTest string
+	// FailureDetail is additional information about why the package failed
+	// (e.g. the first compiler error or the location of a panic).
+	FailureDetail string
+	// CoverageRule is the package coverage rule that applied to the package (if any).
+	CoverageRule string
+	Coverage     float64
+	Line         string
+	// Elapsed is the duration of the package's tests in seconds.
+	Elapsed float64
 }
 
-func (eh *goTestEventHandler) streamFunc(output command.Output, data *command.Data, bLine []byte) error {
-	if eh.err != nil {
-		return nil
-	}
-
-	for _, line := range strings.Split(string(bLine), "\n") {
-		eh.err = eh.processLine(line)
-		if eh.err != nil {
-			break
-		}
-	}
-
-	return nil
-}
-
-func (eh *goTestEventHandler) processLine(line string) error {
-	if m := noTestRegex.FindStringSubmatch(line); m != nil {
-		return eh.setPackageResult(m[1], line, noTestFiles, 0)
-	}
-
-	if m := coverageRegex.FindStringSubmatch(line); m != nil {
-		coverage, err := strconv.ParseFloat(m[2], 64)
-		if err != nil {
-			return fmt.Errorf("failed to parse coverage value: %v", err)
-		}
-		return eh.setPackageResult(m[1], line, testSuccess, coverage)
-	}
-
-	if m := testFailRegex.FindStringSubmatch(line); m != nil {
-		return eh.setPackageResult(m[1], line, testFailure, 0)
+func (gc *goCLI) Node() command.Node {
+	return &commander.BranchNode{
+		Branches: map[string]command.Node{
+			"list":  listNode(),
+			"merge": mergeNode(),
+		},
+		Default:           testNode(),
+		DefaultCompletion: true,
 	}
-
-	return nil
 }
 
-func (gc *goCLI) Node() command.Node {
+// testNode returns the node that runs `go test` and checks coverage.
+func testNode() command.Node {
 	return commander.SerialNodes(
 		commander.FlagProcessor(
 			minCoverageFlag,
 			verboseFlag,
+			quietFlag,
 			timeoutFlag,
 			funcFilterFlag,
+			skipFlag,
+			failedFlag,
 			packageCountFlag,
+			coverageRuleFlag,
+			excludeFlag,
+			diffBaseFlag,
+			noRegressFlag,
+			regressTolFlag,
+			uncoveredFlag,
+			snippetsFlag,
+			funcsFlag,
+			requireExpFlag,
+			noRequireExpFlag,
+			crossPackageFlag,
+			coverPkgFlag,
+			coverProfileFlag,
+			htmlFlag,
+			badgeFlag,
+			lcovFlag,
+			coberturaFlag,
+			junitFlag,
+			jsonOutFlag,
 		),
 		pathArgs,
+		commander.SuperSimpleProcessor(applyConfig),
 		&commander.ExecutorProcessor{F: func(o command.Output, d *command.Data) error {
-			// Error if verbose and coverage check
-			mc := minCoverageFlag.Get(d)
-
-			// Construct go test
-			args := []string{
-				"test",
+			mod, err := currentGoModule()
+			if err != nil {
+				return o.Annotatef(err, "failed to find go module")
 			}
-			if d.Has(timeoutFlag.Name()) {
-				args = append(args, "-timeout", fmt.Sprintf("%ds", timeoutFlag.Get(d)))
+			wd, err := getwd()
+			if err != nil {
+				return o.Annotatef(err, "failed to get current directory")
 			}
-			args = append(args, pathArgs.Get(d)...)
-			if verboseFlag.Get(d) {
-				args = append(args, "-v")
+			policy, err := newCoveragePolicy(o, d, mod, wd)
+			if err != nil {
+				return err
+			}
+
+			if crossPackageFlag.Get(d) && coverPkgFlag.Provided(d) {
+				return o.Stderrln("Cannot set cross-package and coverpkg flags simultaneously")
 			}
-			if d.Has(funcFilterFlag.Name()) {
-				if mc > 0.0 {
-					return o.Stderrln("Cannot set func-filter and min coverage flags simultaneously")
+
+			// Construct go test
+			var runs [][]string
+			var coverProfileFile string
+			if failedFlag.Get(d) {
+				if d.Has(funcFilterFlag.Name()) {
+					return o.Stderrln("Cannot set failed and func-filter flags simultaneously")
 				}
-				parens := fmt.Sprintf("(%s)", strings.Join(funcFilterFlag.Get(d), "|"))
-				args = append(args, "-run", parens)
-			} else {
-				// TODO: Use tmpFile to compute coverage data instead of parsing somewhat arbitrary text (which is viable change (already happened once on me))
-				tmp, err := tmpFile()
+				lf, err := loadFailures(mod, wd)
 				if err != nil {
-					return o.Annotatef(err, "failed to create temporary file")
+					return o.Annotatef(err, "failed to load failed tests")
+				}
+				if len(lf.Packages) == 0 {
+					o.Stdoutln("No failed tests from the previous run")
+					return nil
+				}
+				// The -run flag applies to every package, so each package is run separately.
+				for _, p := range lf.sortedPackages() {
+					args := goTestArgs(d, []string{p})
+					if tests := lf.Packages[p]; len(tests) > 0 {
+						args = append(args, "-run", exactTestsPattern(tests))
+					}
+					runs = append(runs, args)
+				}
+			} else {
+				args := goTestArgs(d, pathArgs.Get(d))
+				if d.Has(funcFilterFlag.Name()) {
+					if policy.hasThresholds() {
+						return o.Stderrln("Cannot set func-filter and min coverage flags simultaneously")
+					}
+					args = append(args, "-run", funcFilterPattern(funcFilterFlag.Get(d)))
+				} else {
+					if d.Has(skipFlag.Name()) {
+						o.Stderrln("Coverage will be partial since skipped tests don't contribute to it")
+					}
+					if coverProfileFlag.Provided(d) {
+						coverProfileFile = coverProfileFlag.Get(d)
+					} else {
+						tmp, err := tmpFile()
+						if err != nil {
+							return o.Annotatef(err, "failed to create temporary file")
+						}
+						// go test writes the profile itself, so the file is only needed for its name.
+						tmp.Close()
+						defer os.Remove(tmp.Name())
+						coverProfileFile = tmp.Name()
+					}
+					args = append(args, fmt.Sprintf("-coverprofile=%s", coverProfileFile))
+					// The merged profile contains blocks from every test binary, so
+					// per-package coverage computed below credits code tested from
+					// other packages.
+					if coverPkgFlag.Provided(d) {
+						args = append(args, fmt.Sprintf("-coverpkg=%s", coverPkgFlag.Get(d)))
+					} else if crossPackageFlag.Get(d) {
+						args = append(args, fmt.Sprintf("-coverpkg=%s", strings.Join(pathArgs.Get(d), ",")))
+					}
 				}
-				args = append(args, fmt.Sprintf("-coverprofile=%s", tmp.Name()))
+				runs = append(runs, args)
 			}
 
-			// Run the command
-			eh := &goTestEventHandler{
-				packageResults: map[string]*packageResult{},
+			var gitRoot string
+			var changes changedLines
+			if diffBaseFlag.Provided(d) {
+				if gitRoot, changes, err = gitChanges(o, d, diffBaseFlag.Get(d)); err != nil {
+					return o.Err(err)
+				}
 			}
-			sc := &commander.ShellCommand[[]string]{
-				CommandName:           "go",
-				Args:                  args,
-				OutputStreamProcessor: eh.streamFunc,
-				ForwardStdout:         true,
+
+			// Run the command
+			eh := newGoTestEventHandler(verboseFlag.Get(d), quietFlag.Get(d))
+			var runErr error
+			for _, args := range runs {
+				sc := &commander.ShellCommand[[]string]{
+					CommandName:           "go",
+					Args:                  args,
+					OutputStreamProcessor: eh.streamFunc,
+				}
+				if _, err := sc.Run(o, d); err != nil {
+					runErr = err
+				}
+				eh.flush(o)
 			}
-			if _, err := sc.Run(o, d); err != nil {
-				return o.Annotatef(err, "go test shell command error")
+			// `go test` exits with an error when tests fail, so only return the
+			// error if no results were produced.
+			if runErr != nil && len(eh.packageResults) == 0 {
+				return o.Annotatef(runErr, "go test shell command error")
 			}
 			if eh.err != nil {
 				return o.Annotatef(eh.err, "event handling error")
 			}
 
+			eh.classifyFailures(mod)
+			markNoTestFiles(eh.packageResults, mod)
+			tests := eh.testResults()
+
+			// Saving failures is best effort, so errors don't fail the run.
+			if err := saveFailures(mod, wd, eh.packageResults, tests); err != nil {
+				o.Stderrf("failed to save failed tests: %v\n", err)
+			}
+
+			// Compute coverage
+			var report *coverageReport
+			if coverProfileFile != "" {
+				cp, err := readCoverProfile(coverProfileFile)
+				if err != nil {
+					return o.Annotatef(err, "failed to read coverage profile")
+				}
+				report = policy.report(cp, mod)
+				for p, pr := range eh.packageResults {
+					if pc := report.packageCoverage(p); pc != nil {
+						pr.Coverage = pc.Counts.Percent()
+					}
+				}
+				if report.Excluded > 0 {
+					o.Stdoutf("Excluded %d statements from coverage\n", report.Excluded)
+				}
+			}
+
+			var dc *diffCoverage
+			if report != nil && changes != nil {
+				dc = computeDiffCoverage(report, gitRoot, changes)
+			}
+
 			// Error to return
 			packages := maps.Keys(eh.packageResults)
 			slices.Sort(packages)
 
-			if packageCountFlag.Provided(d) {
-				if expectedPackageCount := packageCountFlag.Get(d); expectedPackageCount != len(packages) {
-					return o.Stderrf("Expected %d packages, got %d:\n%s\n", expectedPackageCount, len(packages), strings.Join(packages, "\n"))
+			writeReports := func() error {
+				if junitFlag.Provided(d) {
+					if err := writeJUnitReport(junitFlag.Get(d), junitReport(packages, eh.packageResults, tests)); err != nil {
+						return o.Annotatef(err, "failed to write JUnit report")
+					}
 				}
+				if report != nil {
+					if err := writeCoverageReports(o, d, report, mod); err != nil {
+						return err
+					}
+				}
+				if jsonOutFlag.Provided(d) {
+					if err := writeJSONReport(jsonOutFlag.Get(d), newJSONReport(runs, coverProfileFlag.Get(d), packages, eh.packageResults, tests, report)); err != nil {
+						return o.Annotatef(err, "failed to write JSON report")
+					}
+				}
+				return nil
 			}
 
-			var retErr error
-			for _, p := range packages {
-				pr := eh.packageResults[p]
-				switch pr.TestResult {
-				case noTestFiles:
-				case testFailure:
-					retErr = o.Stderrf("Tests failed for package: %s\n", p)
-				case testSuccess:
-					if pr.Coverage < mc {
-						retErr = o.Stderrf("Coverage of package %q (%s) must be at least %s\n", p, percentFormat(pr.Coverage), percentFormat(mc))
-						continue
+			// Only the packages with failures are rerun with the failed flag.
+			if packageCountFlag.Provided(d) && !failedFlag.Get(d) {
+				if expectedPackageCount := packageCountFlag.Get(d); expectedPackageCount != len(packages) {
+					err := o.Stderrf("Expected %d packages, got %d:\n%s\n", expectedPackageCount, len(packages), strings.Join(packages, "\n"))
+					// The reports are still written so they're available for the failed run.
+					if werr := writeReports(); werr != nil {
+						return werr
 					}
+					return err
+				}
+			}
+
+			retErr := policy.check(o, packages, eh.packageResults, report, dc, mod)
+
+			// Coverage of the packages that passed (used for the history checks).
+			coverage := map[string]float64{}
+			for p, pr := range eh.packageResults {
+				if pc := report.packageCoverage(p); pr.TestResult == testSuccess && pc != nil && pc.Counts.Statements > 0 {
+					coverage[p] = pr.Coverage
 				}
 			}
 
+			// Only successful runs are recorded. Skipped tests don't contribute to
+			// coverage, so those runs would lower the baseline.
+			recordHistory := retErr == nil && runErr == nil && len(coverage) > 0 && !d.Has(skipFlag.Name())
+			var history *historyStore
+			if report != nil && (noRegressFlag.Get(d) || recordHistory) {
+				// Coverage history is best effort (e.g. it's unavailable outside of a
+				// git repository), so it's only required by the no-regress flag.
+				if history, err = openHistory(o, d, mod); err != nil && noRegressFlag.Get(d) {
+					return o.Annotatef(err, "failed to load coverage history")
+				}
+			}
+			if history != nil && noRegressFlag.Get(d) {
+				if err := checkRegressions(o, coverage, history.baseline(), regressTolFlag.GetOrDefault(d, 0)); err != nil {
+					retErr = err
+				}
+			}
+
+			if retErr == nil && runErr != nil {
+				retErr = o.Annotatef(runErr, "go test shell command error")
+			}
+
+			// Coverage of changes that aren't committed isn't recorded since it
+			// doesn't reflect the commit.
+			if recordHistory && retErr == nil && history != nil && !history.dirty {
+				if err := history.record(coverage); err != nil {
+					o.Stderrf("failed to record coverage history: %v\n", err)
+				}
+			}
+
+			if report != nil && uncoveredFlag.Get(d) {
+				printUncovered(o, uncoveredFiles(report, mod), snippetsFlag.Get(d))
+			}
+			if report != nil && funcsFlag.Get(d) {
+				printFuncCoverage(o, funcListings(report, mod))
+			}
+
+			printFailureSummary(o, packages, tests)
+
+			if err := writeReports(); err != nil {
+				return err
+			}
+
 			// Set data for use in tests
+			if coverProfileFlag.Provided(d) && coverProfileFile != "" {
+				d.Set("COVERPROFILE", coverProfileFile)
+			}
 			if len(eh.packageResults) > 0 {
 				d.Set("COVERAGE", eh.packageResults)
 			}
+			if len(tests) > 0 {
+				d.Set("TESTS", tests)
+			}
 
 			return retErr
 		}},
 	)
 }
+
+// writeCoverageReports writes the coverage report in every format requested
+// by the provided flags.
+func writeCoverageReports(o command.Output, d *command.Data, report *coverageReport, mod *goModule) error {
+	if htmlFlag.Provided(d) {
+		if err := writeHTMLReport(htmlFlag.Get(d), report, mod); err != nil {
+			return o.Annotatef(err, "failed to write HTML report")
+		}
+	}
+	if badgeFlag.Provided(d) {
+		if err := writeCoverageBadge(badgeFlag.Get(d), report.Counts.Percent(), minCoverageFlag.Get(d)); err != nil {
+			return o.Annotatef(err, "failed to write coverage badge")
+		}
+	}
+	if lcovFlag.Provided(d) {
+		if err := writeLCOVReport(lcovFlag.Get(d), report, mod); err != nil {
+			return o.Annotatef(err, "failed to write LCOV report")
+		}
+	}
+	if coberturaFlag.Provided(d) {
+		if err := writeCoberturaReport(coberturaFlag.Get(d), report, mod); err != nil {
+			return o.Annotatef(err, "failed to write Cobertura report")
+		}
+	}
+	return nil
+}
+
+// goTestArgs returns the `go test` arguments for the provided packages.
+func goTestArgs(d *command.Data, paths []string) []string {
+	args := []string{
+		"test",
+		"-json",
+	}
+	if d.Has(timeoutFlag.Name()) {
+		args = append(args, "-timeout", fmt.Sprintf("%ds", timeoutFlag.Get(d)))
+	}
+	args = append(args, paths...)
+	if d.Has(skipFlag.Name()) {
+		args = append(args, "-skip", skipPattern(skipFlag.Get(d)))
+	}
+	if verboseFlag.Get(d) {
+		args = append(args, "-v")
+	}
+	return args
+}
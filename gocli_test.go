@@ -1,6 +1,7 @@
 package gocli
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,23 +13,120 @@ import (
 	"github.com/leep-frog/command/commandtest"
 )
 
+func TestMain(m *testing.M) {
+	// Failed tests are saved after every run, so keep them out of the real cache.
+	cacheDir, err := os.MkdirTemp("", "gocli-cache")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create cache directory: %v\n", err)
+		os.Exit(1)
+	}
+	userCacheDir = func() (string, error) { return cacheDir, nil }
+	code := m.Run()
+	os.RemoveAll(cacheDir)
+	os.Exit(code)
+}
+
+func jsonEvent(e *testEvent) string {
+	b, err := json.Marshal(e)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal test event: %v", err))
+	}
+	return string(b)
+}
+
+func outputEvent(pkg, test, output string) string {
+	return jsonEvent(&testEvent{Action: actionOutput, Package: pkg, Test: test, Output: output + "\n"})
+}
+
+func actionEvent(pkg, test, action string) string {
+	return jsonEvent(&testEvent{Action: action, Package: pkg, Test: test})
+}
+
+// packageEvents returns the events for package-level output lines
+// followed by the terminal package action.
+func packageEvents(pkg, action string, lines ...string) []string {
+	var r []string
+	for _, line := range lines {
+		r = append(r, outputEvent(pkg, "", line))
+	}
+	return append(r, actionEvent(pkg, "", action))
+}
+
 func successOutput(pkg string, coverage float64) string {
-	return fmt.Sprintf("ok \t %s \t 0.123s \t coverage: \t %0.2f%% of statements", pkg, coverage)
+	return fmt.Sprintf("ok  \t%s\t0.123s\tcoverage: %0.2f%% of statements", pkg, coverage)
 }
 
 func failLine(pkg string) string {
-	return fmt.Sprintf("FAIL \t %s \t abc \t 123 def", pkg)
+	return fmt.Sprintf("FAIL\t%s\t0.123s", pkg)
 }
 
 func noTestLine(pkg string) string {
-	return fmt.Sprintf("?       %s        [no test files]", pkg)
+	return fmt.Sprintf("?   \t%s\t[no test files]", pkg)
+}
+
+func successEvents(pkg string, coverage float64) []string {
+	return packageEvents(pkg, actionPass, "PASS", fmt.Sprintf("coverage: %0.2f%% of statements", coverage), successOutput(pkg, coverage))
+}
+
+func failEvents(pkg string) []string {
+	return packageEvents(pkg, actionFail, "FAIL", failLine(pkg))
+}
+
+func noTestEvents(pkg string) []string {
+	return packageEvents(pkg, actionSkip, noTestLine(pkg))
+}
+
+// coverNoTestLine is the line go 1.22+ prints for a package without test files
+// when coverage is enabled.
+func coverNoTestLine(pkg string) string {
+	return fmt.Sprintf("\t%s\t\tcoverage: 0.0%% of statements", pkg)
+}
+
+// coverNoTestEvents are the events go 1.22+ sends for a package without test
+// files when coverage is enabled (the package passes instead of being skipped).
+func coverNoTestEvents(pkg string) []string {
+	return packageEvents(pkg, actionPass, coverNoTestLine(pkg))
+}
+
+// profileBlocks returns coverage profile lines for a package with the
+// provided number of covered and total single-statement blocks.
+func profileBlocks(pkg string, covered, total int) []string {
+	var r []string
+	for i := 0; i < total; i++ {
+		count := 0
+		if i < covered {
+			count = 1
+		}
+		r = append(r, fmt.Sprintf("%s/file.go:%d.2,%d.10 1 %d", pkg, i+1, i+1, count))
+	}
+	return r
+}
+
+func profileLines(mode string, blocks ...[]string) []string {
+	return append([]string{fmt.Sprintf("mode: %s", mode)}, concat(blocks...)...)
+}
+
+func concat(sls ...[]string) []string {
+	var r []string
+	for _, sl := range sls {
+		r = append(r, sl...)
+	}
+	return r
+}
+
+func stdoutLines(lines ...string) string {
+	return strings.Join(lines, "\n") + "\n"
 }
 
 func TestExecute(t *testing.T) {
 	for _, test := range []struct {
-		name       string
-		etc        *commandtest.ExecuteTestCase
-		tmpFileErr error
+		name         string
+		etc          *commandtest.ExecuteTestCase
+		tmpFileErr   error
+		coverProfile []string
+		// recordsHistory is whether the run records coverage history (which
+		// runs `git status` after `go test`).
+		recordsHistory bool
 	}{
 		{
 			name: "Works when no coverage returned",
@@ -38,32 +136,841 @@ func TestExecute(t *testing.T) {
 					Name: "go",
 					Args: []string{
 						"test",
+						"-json",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+				}},
+			},
+		},
+		{
+			name: "Fails if shell command error",
+			etc: &commandtest.ExecuteTestCase{
+				WantErr:    fmt.Errorf("go test shell command error: failed to execute shell command: bad news bears"),
+				WantStderr: "go test shell command error: failed to execute shell command: bad news bears\n",
+				RunResponses: []*commandtest.FakeRun{{
+					Err: fmt.Errorf("bad news bears"),
+				}},
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
 						".",
 						"-coverprofile=(TMP_FILE)",
 					},
 				}},
 				WantData: &command.Data{Values: map[string]interface{}{
 					pathArgs.Name():        []string{"."},
-					minCoverageFlag.Name(): 0.0,
+					minCoverageFlag.Name(): 0.0,
+				}},
+			},
+		},
+		{
+			name: "Forwards output that isn't a test event",
+			etc: &commandtest.ExecuteTestCase{
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: []string{
+						"hello there",
+						"general kenobi",
+						"{not json",
+					},
+				}},
+				WantStdout: stdoutLines(
+					"hello there",
+					"general kenobi",
+					"{not json",
+				),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+				}},
+			},
+		},
+		{
+			name:           "Gets coverage result",
+			recordsHistory: true,
+			coverProfile:   profileLines("set", profileBlocks("p1", 1, 8)),
+			etc: &commandtest.ExecuteTestCase{
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: concat(
+						[]string{"hello there"},
+						successEvents("p1", 12.5),
+						[]string{"general kenobi"},
+					),
+				}},
+				WantStdout: stdoutLines(
+					"hello there",
+					successOutput("p1", 12.5),
+					"general kenobi",
+				),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: testSuccess,
+							Coverage:   12.5,
+							Line:       successOutput("p1", 12.5),
+						},
+					},
+				}},
+			},
+		},
+		{
+			name: "Gets no-test result",
+			etc: &commandtest.ExecuteTestCase{
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: concat(
+						[]string{"hello there"},
+						noTestEvents("p1"),
+						[]string{"general kenobi"},
+					),
+				}},
+				WantStdout: stdoutLines(
+					"hello there",
+					noTestLine("p1"),
+					"general kenobi",
+				),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: noTestFiles,
+							Coverage:   0.0,
+							Line:       noTestLine("p1"),
+						},
+					},
+				}},
+			},
+		},
+		{
+			name: "Gets test failure result",
+			etc: &commandtest.ExecuteTestCase{
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: concat(
+						[]string{"hello there"},
+						failEvents("p1"),
+						[]string{"general kenobi"},
+					),
+				}},
+				WantStdout: stdoutLines(
+					"hello there",
+					"FAIL",
+					failLine("p1"),
+					"general kenobi",
+				),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantErr:    fmt.Errorf("Tests failed for package: p1"),
+				WantStderr: "Tests failed for package: p1\n",
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: testFailure,
+							Coverage:   0.0,
+							Line:       failLine("p1"),
+						},
+					},
+				}},
+			},
+		},
+		{
+			name: "Only prints output of failing tests",
+			etc: &commandtest.ExecuteTestCase{
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: concat(
+						[]string{
+							actionEvent("p1", "TestOne", "run"),
+							outputEvent("p1", "TestOne", "=== RUN   TestOne"),
+							outputEvent("p1", "TestOne", "--- PASS: TestOne (0.00s)"),
+							actionEvent("p1", "TestOne", actionPass),
+							actionEvent("p1", "TestTwo", "run"),
+							outputEvent("p1", "TestTwo", "=== RUN   TestTwo"),
+							outputEvent("p1", "TestTwo", "    two_test.go:12: oh no"),
+							outputEvent("p1", "TestTwo", "--- FAIL: TestTwo (0.00s)"),
+							actionEvent("p1", "TestTwo", actionFail),
+							actionEvent("p1", "TestThree", "run"),
+							outputEvent("p1", "TestThree", "=== RUN   TestThree"),
+							outputEvent("p1", "TestThree", "--- SKIP: TestThree (0.00s)"),
+							actionEvent("p1", "TestThree", actionSkip),
+						},
+						failEvents("p1"),
+					),
+				}},
+				WantStdout: stdoutLines(
+					"    two_test.go:12: oh no",
+					"--- FAIL: TestTwo (0.00s)",
+					"FAIL",
+					failLine("p1"),
+					"Failure summary:",
+					"  p1",
+					"    TestTwo",
+					"      two_test.go:12: oh no",
+				),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantErr:    fmt.Errorf("Tests failed for package: p1"),
+				WantStderr: "Tests failed for package: p1\n",
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: testFailure,
+							Coverage:   0.0,
+							Line:       failLine("p1"),
+						},
+					},
+					"TESTS": map[string][]*testCaseResult{
+						"p1": {
+							{
+								Name:   "TestOne",
+								Status: testPassed,
+								Output: []string{"=== RUN   TestOne", "--- PASS: TestOne (0.00s)"},
+							},
+							{
+								Name:   "TestTwo",
+								Status: testFailed,
+								Output: []string{"=== RUN   TestTwo", "    two_test.go:12: oh no", "--- FAIL: TestTwo (0.00s)"},
+							},
+							{
+								Name:   "TestThree",
+								Status: testSkipped,
+								Output: []string{"=== RUN   TestThree", "--- SKIP: TestThree (0.00s)"},
+							},
+						},
+					},
+				}},
+			},
+		},
+		{
+			name: "Prints output of tests that never finished",
+			etc: &commandtest.ExecuteTestCase{
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: concat(
+						[]string{
+							actionEvent("p1", "TestOne", "run"),
+							outputEvent("p1", "TestOne", "=== RUN   TestOne"),
+							outputEvent("p1", "TestOne", "panic: runtime error: invalid memory address or nil pointer dereference"),
+							outputEvent("p1", "TestOne", "goroutine 51 [running]:"),
+						},
+						failEvents("p1"),
+					),
+				}},
+				WantStdout: stdoutLines(
+					"panic: runtime error: invalid memory address or nil pointer dereference",
+					"goroutine 51 [running]:",
+					"FAIL",
+					failLine("p1"),
+					"Failure summary:",
+					"  p1",
+					"    TestOne",
+					"      panic: runtime error: invalid memory address or nil pointer dereference",
+					"      goroutine 51 [running]:",
+				),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantErr:    fmt.Errorf("Test panicked in package p1: TestOne"),
+				WantStderr: "Test panicked in package p1: TestOne\n",
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: testPanic,
+							FailedTest: "TestOne",
+							Coverage:   0.0,
+							Line:       failLine("p1"),
+						},
+					},
+					"TESTS": map[string][]*testCaseResult{
+						"p1": {{
+							Name:   "TestOne",
+							Status: testRunning,
+							Output: []string{
+								"=== RUN   TestOne",
+								"panic: runtime error: invalid memory address or nil pointer dereference",
+								"goroutine 51 [running]:",
+							},
+						}},
+					},
+				}},
+			},
+		},
+		{
+			name: "Reports build failures",
+			etc: &commandtest.ExecuteTestCase{
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: concat(
+						[]string{
+							jsonEvent(&testEvent{Action: actionBuildOutput, ImportPath: "p1 [p1.test]", Output: "# p1 [p1.test]\n"}),
+							jsonEvent(&testEvent{Action: actionBuildOutput, ImportPath: "p1 [p1.test]", Output: "p1/file.go:3:23: undefined: x\n"}),
+							jsonEvent(&testEvent{Action: "build-fail", ImportPath: "p1 [p1.test]"}),
+							outputEvent("p1", "", "FAIL\tp1 [build failed]"),
+							jsonEvent(&testEvent{Action: actionFail, Package: "p1", FailedBuild: "p1 [p1.test]"}),
+						},
+						successEvents("p2", 100),
+					),
+				}},
+				WantStdout: stdoutLines(
+					"# p1 [p1.test]",
+					"p1/file.go:3:23: undefined: x",
+					"FAIL\tp1 [build failed]",
+					successOutput("p2", 100),
+				),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantErr:    fmt.Errorf("Build failed for package: p1 (p1/file.go:3:23: undefined: x)"),
+				WantStderr: "Build failed for package: p1 (p1/file.go:3:23: undefined: x)\n",
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult:    buildFailure,
+							FailureDetail: "p1/file.go:3:23: undefined: x",
+							Line:          "FAIL\tp1 [build failed]",
+						},
+						"p2": {
+							TestResult: testSuccess,
+							Line:       successOutput("p2", 100),
+						},
+					},
+				}},
+			},
+		},
+		{
+			name: "Reports setup failures printed as plain text",
+			etc: &commandtest.ExecuteTestCase{
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: []string{"FAIL\tp1 [setup failed]"},
+					Err:    fmt.Errorf("exit status 1"),
+				}},
+				WantStdout: stdoutLines(
+					"FAIL\tp1 [setup failed]",
+				),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantErr:    fmt.Errorf("Setup failed for package: p1"),
+				WantStderr: "Setup failed for package: p1\n",
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: setupFailure,
+							Line:       "FAIL\tp1 [setup failed]",
+						},
+					},
+				}},
+			},
+		},
+		{
+			name: "Reports timeouts",
+			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"-t", "1"},
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: concat(
+						[]string{
+							actionEvent("p1", "TestSlow", "run"),
+							outputEvent("p1", "TestSlow", "=== RUN   TestSlow"),
+							outputEvent("p1", "TestSlow", "panic: test timed out after 1s"),
+							outputEvent("p1", "TestSlow", "\trunning tests:"),
+							outputEvent("p1", "TestSlow", "\t\tTestSlow (1s)"),
+						},
+						failEvents("p1"),
+					),
+				}},
+				WantStdout: stdoutLines(
+					"panic: test timed out after 1s",
+					"\trunning tests:",
+					"\t\tTestSlow (1s)",
+					"FAIL",
+					failLine("p1"),
+					"Failure summary:",
+					"  p1",
+					"    TestSlow",
+					"      panic: test timed out after 1s",
+					"      \trunning tests:",
+					"      \t\tTestSlow (1s)",
+				),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						"-timeout",
+						"1s",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantErr:    fmt.Errorf("Test timed out in package p1: TestSlow (after 1s)"),
+				WantStderr: "Test timed out in package p1: TestSlow (after 1s)\n",
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					timeoutFlag.Name():     1,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult:    testTimeout,
+							FailedTest:    "TestSlow",
+							FailureDetail: "after 1s",
+							Line:          failLine("p1"),
+						},
+					},
+					"TESTS": map[string][]*testCaseResult{
+						"p1": {{
+							Name:   "TestSlow",
+							Status: testRunning,
+							Output: []string{
+								"=== RUN   TestSlow",
+								"panic: test timed out after 1s",
+								"\trunning tests:",
+								"\t\tTestSlow (1s)",
+							},
+						}},
+					},
+				}},
+			},
+		},
+		{
+			name: "Records subtest results",
+			etc: &commandtest.ExecuteTestCase{
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: concat(
+						[]string{
+							actionEvent("p1", "TestOne", "run"),
+							outputEvent("p1", "TestOne", "=== RUN   TestOne"),
+							actionEvent("p1", "TestOne/first_case", "run"),
+							outputEvent("p1", "TestOne/first_case", "=== RUN   TestOne/first_case"),
+							outputEvent("p1", "TestOne/first_case", "    one_test.go:12: oh no"),
+							actionEvent("p1", "TestOne/second_case", "run"),
+							outputEvent("p1", "TestOne/second_case", "=== RUN   TestOne/second_case"),
+							outputEvent("p1", "TestOne", "--- FAIL: TestOne (0.30s)"),
+							jsonEvent(&testEvent{Action: actionFail, Package: "p1", Test: "TestOne", Elapsed: 0.3}),
+							outputEvent("p1", "TestOne/first_case", "    --- FAIL: TestOne/first_case (0.10s)"),
+							jsonEvent(&testEvent{Action: actionFail, Package: "p1", Test: "TestOne/first_case", Elapsed: 0.1}),
+							outputEvent("p1", "TestOne/second_case", "    --- PASS: TestOne/second_case (0.20s)"),
+							jsonEvent(&testEvent{Action: actionPass, Package: "p1", Test: "TestOne/second_case", Elapsed: 0.2}),
+							outputEvent("p1", "", "FAIL"),
+							outputEvent("p1", "", failLine("p1")),
+							jsonEvent(&testEvent{Action: actionFail, Package: "p1", Elapsed: 0.5}),
+						},
+					),
+				}},
+				WantStdout: stdoutLines(
+					"--- FAIL: TestOne (0.30s)",
+					"    one_test.go:12: oh no",
+					"    --- FAIL: TestOne/first_case (0.10s)",
+					"FAIL",
+					failLine("p1"),
+					"Failure summary:",
+					"  p1",
+					"    TestOne/first_case",
+					"      one_test.go:12: oh no",
+				),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantErr:    fmt.Errorf("Tests failed for package: p1"),
+				WantStderr: "Tests failed for package: p1\n",
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: testFailure,
+							Line:       failLine("p1"),
+							Elapsed:    0.5,
+						},
+					},
+					"TESTS": map[string][]*testCaseResult{
+						"p1": {{
+							Name:    "TestOne",
+							Status:  testFailed,
+							Elapsed: 0.3,
+							Output:  []string{"=== RUN   TestOne", "--- FAIL: TestOne (0.30s)"},
+							Subtests: []*testCaseResult{
+								{
+									Name:    "TestOne/first_case",
+									Status:  testFailed,
+									Elapsed: 0.1,
+									Output:  []string{"=== RUN   TestOne/first_case", "    one_test.go:12: oh no", "    --- FAIL: TestOne/first_case (0.10s)"},
+								},
+								{
+									Name:    "TestOne/second_case",
+									Status:  testPassed,
+									Elapsed: 0.2,
+									Output:  []string{"=== RUN   TestOne/second_case", "    --- PASS: TestOne/second_case (0.20s)"},
+								},
+							},
+						}},
+					},
+				}},
+			},
+		},
+		{
+			name: "Only prints failure summary if quiet",
+			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"-q"},
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: concat(
+						[]string{
+							"not an event",
+							actionEvent("p1", "TestOne", "run"),
+							outputEvent("p1", "TestOne", "=== RUN   TestOne"),
+							outputEvent("p1", "TestOne", "    one_test.go:12: some log"),
+							outputEvent("p1", "TestOne", "--- PASS: TestOne (0.00s)"),
+							actionEvent("p1", "TestOne", actionPass),
+							actionEvent("p2", "TestTwo", "run"),
+							outputEvent("p2", "TestTwo", "=== RUN   TestTwo"),
+							outputEvent("p2", "TestTwo", "    two_test.go:12: Unexpected diff:"),
+							outputEvent("p2", "TestTwo", "          want"),
+							outputEvent("p2", "TestTwo", "        + got"),
+							outputEvent("p2", "TestTwo", ""),
+							outputEvent("p2", "TestTwo", "--- FAIL: TestTwo (0.00s)"),
+							actionEvent("p2", "TestTwo", actionFail),
+						},
+						successEvents("p1", 12.5),
+						failEvents("p2"),
+					),
+					Err: fmt.Errorf("exit status 1"),
+				}},
+				WantStdout: stdoutLines(
+					"Failure summary:",
+					"  p2",
+					"    TestTwo",
+					"      two_test.go:12: Unexpected diff:",
+					"            want",
+					"          + got",
+				),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantErr:    fmt.Errorf("Tests failed for package: p2"),
+				WantStderr: "Tests failed for package: p2\n",
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					quietFlag.Name():       true,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: testSuccess,
+							Line:       successOutput("p1", 12.5),
+						},
+						"p2": {
+							TestResult: testFailure,
+							Line:       failLine("p2"),
+						},
+					},
+					"TESTS": map[string][]*testCaseResult{
+						"p1": {{
+							Name:   "TestOne",
+							Status: testPassed,
+							Output: []string{"=== RUN   TestOne", "    one_test.go:12: some log", "--- PASS: TestOne (0.00s)"},
+						}},
+						"p2": {{
+							Name:   "TestTwo",
+							Status: testFailed,
+							Output: []string{
+								"=== RUN   TestTwo",
+								"    two_test.go:12: Unexpected diff:",
+								"          want",
+								"        + got",
+								"",
+								"--- FAIL: TestTwo (0.00s)",
+							},
+						}},
+					},
+				}},
+			},
+		},
+		{
+			name: "Fails if shell command error and no test failures",
+			etc: &commandtest.ExecuteTestCase{
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: noTestEvents("p1"),
+					Err:    fmt.Errorf("exit status 2"),
+				}},
+				WantStdout: stdoutLines(noTestLine("p1")),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantErr:    fmt.Errorf("go test shell command error: failed to execute shell command: exit status 2"),
+				WantStderr: "go test shell command error: failed to execute shell command: exit status 2\n",
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: noTestFiles,
+							Line:       noTestLine("p1"),
+						},
+					},
+				}},
+			},
+		},
+		{
+			name: "Adds timeout flag",
+			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"-t", "123"},
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: noTestEvents("p1"),
+				}},
+				WantStdout: stdoutLines(noTestLine("p1")),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						"-timeout",
+						"123s",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					timeoutFlag.Name():     123,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: noTestFiles,
+							Coverage:   0.0,
+							Line:       noTestLine("p1"),
+						},
+					},
+				}},
+			},
+		},
+		{
+			name:           "Adds verbose flag",
+			recordsHistory: true,
+			coverProfile:   profileLines("set", profileBlocks("p1", 1, 8)),
+			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"-v"},
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: concat(
+						[]string{
+							actionEvent("p1", "TestOne", "run"),
+							outputEvent("p1", "TestOne", "=== RUN   TestOne"),
+							outputEvent("p1", "TestOne", "    one_test.go:12: some log"),
+							outputEvent("p1", "TestOne", "--- PASS: TestOne (0.00s)"),
+							actionEvent("p1", "TestOne", actionPass),
+						},
+						successEvents("p1", 12.5),
+					),
+				}},
+				WantStdout: stdoutLines(
+					"=== RUN   TestOne",
+					"    one_test.go:12: some log",
+					"--- PASS: TestOne (0.00s)",
+					"PASS",
+					"coverage: 12.50% of statements",
+					successOutput("p1", 12.5),
+				),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-v",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					verboseFlag.Name():     true,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: testSuccess,
+							Coverage:   12.5,
+							Line:       successOutput("p1", 12.5),
+						},
+					},
+					"TESTS": map[string][]*testCaseResult{
+						"p1": {{
+							Name:   "TestOne",
+							Status: testPassed,
+							Output: []string{"=== RUN   TestOne", "    one_test.go:12: some log", "--- PASS: TestOne (0.00s)"},
+						}},
+					},
+				}},
+			},
+		},
+		{
+			name: "Adds func-filter flag",
+			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"-f", "SomeTest", "OtherTest"},
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: noTestEvents("p1"),
+				}},
+				WantStdout: stdoutLines(noTestLine("p1")),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-run",
+						"(SomeTest)|(OtherTest)",
+					},
+				}},
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					funcFilterFlag.Name():  []string{"SomeTest", "OtherTest"},
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: noTestFiles,
+							Coverage:   0.0,
+							Line:       noTestLine("p1"),
+						},
+					},
+				}},
+			},
+		},
+		{
+			name: "Runs subtests with func-filter flag",
+			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"-f", "Execute/some case", "Merge/a.b"},
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: noTestEvents("p1"),
+				}},
+				WantStdout: stdoutLines(noTestLine("p1")),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-run",
+						`(Execute)/^(some_case)$|(Merge)/^(a\.b)$`,
+					},
+				}},
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					funcFilterFlag.Name():  []string{"Execute/some case", "Merge/a.b"},
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: noTestFiles,
+							Line:       noTestLine("p1"),
+						},
+					},
+				}},
+			},
+		},
+		{
+			name: "Fails if func-filter flag and min coverage flag",
+			etc: &commandtest.ExecuteTestCase{
+				Args:       []string{"-m", "12.34", "-f", "SomeTest", "OtherTest"},
+				WantStderr: "Cannot set func-filter and min coverage flags simultaneously\n",
+				WantErr:    fmt.Errorf("Cannot set func-filter and min coverage flags simultaneously"),
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 12.34,
+					funcFilterFlag.Name():  []string{"SomeTest", "OtherTest"},
 				}},
 			},
 		},
 		{
-			name: "Fails if shell command error",
+			name:       "Fails if tmp file error",
+			tmpFileErr: fmt.Errorf("oops"),
 			etc: &commandtest.ExecuteTestCase{
-				WantErr:    fmt.Errorf("go test shell command error: failed to execute shell command: bad news bears"),
-				WantStderr: "go test shell command error: failed to execute shell command: bad news bears\n",
-				RunResponses: []*commandtest.FakeRun{{
-					Err: fmt.Errorf("bad news bears"),
-				}},
-				WantRunContents: []*commandtest.RunContents{{
-					Name: "go",
-					Args: []string{
-						"test",
-						".",
-						"-coverprofile=(TMP_FILE)",
-					},
-				}},
+				WantStderr: "failed to create temporary file: oops\n",
+				WantErr:    fmt.Errorf("failed to create temporary file: oops"),
 				WantData: &command.Data{Values: map[string]interface{}{
 					pathArgs.Name():        []string{"."},
 					minCoverageFlag.Name(): 0.0,
@@ -71,352 +978,488 @@ func TestExecute(t *testing.T) {
 			},
 		},
 		{
-			name: "Ignores test input no coverage returned",
+			name:           "Succeeds if coverage result is above threshold",
+			recordsHistory: true,
+			coverProfile:   profileLines("set", profileBlocks("p1", 55, 100)),
 			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"-m", "54"},
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						"hello there",
-						"general kenobi",
-					},
+					Stdout: successEvents("p1", 55.0),
 				}},
+				WantStdout: stdoutLines(successOutput("p1", 55.0)),
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
+						"-json",
 						".",
 						"-coverprofile=(TMP_FILE)",
 					},
 				}},
 				WantData: &command.Data{Values: map[string]interface{}{
 					pathArgs.Name():        []string{"."},
-					minCoverageFlag.Name(): 0.0,
+					minCoverageFlag.Name(): 54.0,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: testSuccess,
+							Coverage:   55.0,
+							Line:       successOutput("p1", 55.0),
+						},
+					},
 				}},
 			},
 		},
 		{
-			name: "Gets coverage result",
+			name:           "Succeeds if coverage result is at threshold",
+			recordsHistory: true,
+			coverProfile:   profileLines("set", profileBlocks("p1", 54, 100)),
 			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"-m", "54"},
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						"hello there",
-						successOutput("p1", 12.34),
-						"general kenobi",
-					},
+					Stdout: successEvents("p1", 54.0),
 				}},
+				WantStdout: stdoutLines(successOutput("p1", 54.0)),
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
+						"-json",
 						".",
 						"-coverprofile=(TMP_FILE)",
 					},
 				}},
 				WantData: &command.Data{Values: map[string]interface{}{
 					pathArgs.Name():        []string{"."},
-					minCoverageFlag.Name(): 0.0,
+					minCoverageFlag.Name(): 54.0,
 					"COVERAGE": map[string]*packageResult{
 						"p1": {
-							testSuccess,
-							12.34,
-							successOutput("p1", 12.34),
+							TestResult: testSuccess,
+							Coverage:   54.0,
+							Line:       successOutput("p1", 54.0),
 						},
 					},
 				}},
 			},
 		},
 		{
-			name: "Gets no-test result",
+			name:         "Fails if coverage result is below threshold",
+			coverProfile: profileLines("set", profileBlocks("p1", 53, 100)),
 			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"-m", "54"},
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						"hello there",
-						noTestLine("p1"),
-						"general kenobi",
-					},
+					Stdout: successEvents("p1", 53.0),
 				}},
+				WantStdout: stdoutLines(successOutput("p1", 53.0)),
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
+						"-json",
 						".",
 						"-coverprofile=(TMP_FILE)",
 					},
 				}},
+				WantErr:    fmt.Errorf("Coverage of package \"p1\" (53.0%%) must be at least 54.0%%"),
+				WantStderr: "Coverage of package \"p1\" (53.0%) must be at least 54.0%\n",
 				WantData: &command.Data{Values: map[string]interface{}{
 					pathArgs.Name():        []string{"."},
-					minCoverageFlag.Name(): 0.0,
+					minCoverageFlag.Name(): 54.0,
 					"COVERAGE": map[string]*packageResult{
 						"p1": {
-							noTestFiles,
-							0.0,
-							noTestLine("p1"),
+							TestResult: testSuccess,
+							Coverage:   53.0,
+							Line:       successOutput("p1", 53.0),
 						},
 					},
 				}},
 			},
 		},
 		{
-			name: "Gets test failure result",
+			name: "Applies most specific package coverage rule",
+			coverProfile: profileLines("set",
+				profileBlocks(testModulePath+"/a", 6, 10),
+				profileBlocks(testModulePath+"/internal/x", 7, 10),
+				profileBlocks(testModulePath+"/internal/gen", 1, 10),
+			),
 			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"./...", "-m", "50", "-c", "./internal/...=80", "./internal/gen=0"},
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						"hello there",
-						failLine("p1"),
-						"general kenobi",
-					},
+					Stdout: concat(
+						successEvents(testModulePath+"/a", 60),
+						successEvents(testModulePath+"/internal/gen", 10),
+						successEvents(testModulePath+"/internal/x", 70),
+					),
 				}},
+				WantStdout: stdoutLines(
+					successOutput(testModulePath+"/a", 60),
+					successOutput(testModulePath+"/internal/gen", 10),
+					successOutput(testModulePath+"/internal/x", 70),
+				),
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
-						".",
+						"-json",
+						"./...",
 						"-coverprofile=(TMP_FILE)",
 					},
 				}},
-				WantErr:    fmt.Errorf("Tests failed for package: p1"),
-				WantStderr: "Tests failed for package: p1\n",
+				WantErr:    fmt.Errorf("Coverage of package \"%s/internal/x\" (70.0%%) must be at least 80.0%% (rule: ./internal/...=80)", testModulePath),
+				WantStderr: fmt.Sprintf("Coverage of package \"%s/internal/x\" (70.0%%) must be at least 80.0%% (rule: ./internal/...=80)\n", testModulePath),
 				WantData: &command.Data{Values: map[string]interface{}{
-					pathArgs.Name():        []string{"."},
-					minCoverageFlag.Name(): 0.0,
+					pathArgs.Name():         []string{"./..."},
+					minCoverageFlag.Name():  50.0,
+					coverageRuleFlag.Name(): []string{"./internal/...=80", "./internal/gen=0"},
 					"COVERAGE": map[string]*packageResult{
-						"p1": {
-							testFailure,
-							0.0,
-							failLine("p1"),
+						testModulePath + "/a": {
+							TestResult: testSuccess,
+							Coverage:   60.0,
+							Line:       successOutput(testModulePath+"/a", 60),
+						},
+						testModulePath + "/internal/gen": {
+							TestResult:   testSuccess,
+							Coverage:     10.0,
+							CoverageRule: "./internal/gen=0",
+							Line:         successOutput(testModulePath+"/internal/gen", 10),
+						},
+						testModulePath + "/internal/x": {
+							TestResult:   testSuccess,
+							Coverage:     70.0,
+							CoverageRule: "./internal/...=80",
+							Line:         successOutput(testModulePath+"/internal/x", 70),
 						},
 					},
 				}},
 			},
 		},
 		{
-			name: "Adds timeout flag",
+			name: "Fails if invalid package coverage rule",
 			etc: &commandtest.ExecuteTestCase{
-				Args: []string{"-t", "123"},
+				Args:       []string{"-c", "./internal/..."},
+				WantErr:    fmt.Errorf(`invalid coverage rule "./internal/...": expected format PATTERN=PERCENT`),
+				WantStderr: "invalid coverage rule \"./internal/...\": expected format PATTERN=PERCENT\n",
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():         []string{"."},
+					minCoverageFlag.Name():  0.0,
+					coverageRuleFlag.Name(): []string{"./internal/..."},
+				}},
+			},
+		},
+		{
+			name: "Fails if package coverage rule and func-filter",
+			etc: &commandtest.ExecuteTestCase{
+				Args:       []string{"-c", "./...=50", "-f", "TestOne"},
+				WantErr:    fmt.Errorf("Cannot set func-filter and min coverage flags simultaneously"),
+				WantStderr: "Cannot set func-filter and min coverage flags simultaneously\n",
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():         []string{"."},
+					minCoverageFlag.Name():  0.0,
+					coverageRuleFlag.Name(): []string{"./...=50"},
+					funcFilterFlag.Name():   []string{"TestOne"},
+				}},
+			},
+		},
+		{
+			name:           "Credits code tested from other packages with cross-package",
+			recordsHistory: true,
+			// Tests in each package cover some of the other package's code.
+			coverProfile: profileLines("set",
+				profileBlocks("p1", 2, 10),
+				profileBlocks("p2", 0, 10),
+				profileBlocks("p1", 6, 10),
+				profileBlocks("p2", 8, 10),
+			),
+			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"./p1", "./p2", "-x", "-m", "50"},
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						noTestLine("p1"),
-					},
+					Stdout: concat(
+						successEvents("p1", 10),
+						successEvents("p2", 40),
+					),
 				}},
+				WantStdout: stdoutLines(
+					successOutput("p1", 10),
+					successOutput("p2", 40),
+				),
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
-						"-timeout",
-						"123s",
-						".",
+						"-json",
+						"./p1",
+						"./p2",
 						"-coverprofile=(TMP_FILE)",
+						"-coverpkg=./p1,./p2",
 					},
 				}},
 				WantData: &command.Data{Values: map[string]interface{}{
-					pathArgs.Name():        []string{"."},
-					minCoverageFlag.Name(): 0.0,
-					timeoutFlag.Name():     123,
+					pathArgs.Name():         []string{"./p1", "./p2"},
+					minCoverageFlag.Name():  50.0,
+					crossPackageFlag.Name(): true,
 					"COVERAGE": map[string]*packageResult{
 						"p1": {
-							noTestFiles,
-							0.0,
-							noTestLine("p1"),
+							TestResult: testSuccess,
+							Coverage:   60.0,
+							Line:       successOutput("p1", 10),
+						},
+						"p2": {
+							TestResult: testSuccess,
+							Coverage:   80.0,
+							Line:       successOutput("p2", 40),
 						},
 					},
 				}},
 			},
 		},
 		{
-			name: "Adds verbose flag",
+			name:         "Passes coverpkg pattern to go test",
+			coverProfile: profileLines("set", profileBlocks("p1", 1, 4), profileBlocks("p2", 1, 4)),
 			etc: &commandtest.ExecuteTestCase{
-				Args: []string{"-v"},
+				Args: []string{"--coverpkg", "./...", "-m", "30"},
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						noTestLine("p1"),
-					},
+					Stdout: successEvents("p1", 25),
 				}},
+				WantStdout: stdoutLines(successOutput("p1", 25)),
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
+						"-json",
 						".",
-						"-v",
 						"-coverprofile=(TMP_FILE)",
+						"-coverpkg=./...",
 					},
 				}},
+				WantErr:    fmt.Errorf(`Coverage of package "p1" (25.0%%) must be at least 30.0%%`),
+				WantStderr: "Coverage of package \"p1\" (25.0%) must be at least 30.0%\n",
 				WantData: &command.Data{Values: map[string]interface{}{
 					pathArgs.Name():        []string{"."},
-					minCoverageFlag.Name(): 0.0,
-					verboseFlag.Name():     true,
+					minCoverageFlag.Name(): 30.0,
+					coverPkgFlag.Name():    "./...",
 					"COVERAGE": map[string]*packageResult{
 						"p1": {
-							noTestFiles,
-							0.0,
-							noTestLine("p1"),
+							TestResult: testSuccess,
+							Coverage:   25.0,
+							Line:       successOutput("p1", 25),
 						},
 					},
 				}},
 			},
 		},
 		{
-			name: "Adds func-filter flag",
+			name:         "Skips tests and warns that coverage is partial",
+			coverProfile: profileLines("set", profileBlocks("p1", 1, 4)),
 			etc: &commandtest.ExecuteTestCase{
-				Args: []string{"-f", "SomeTest", "OtherTest"},
+				Args: []string{"--skip", "Slow", "Flaky/some case", "-m", "20"},
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						noTestLine("p1"),
-					},
+					Stdout: successEvents("p1", 25),
 				}},
+				WantStdout: stdoutLines(successOutput("p1", 25)),
+				WantStderr: "Coverage will be partial since skipped tests don't contribute to it\n",
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
+						"-json",
 						".",
-						"-run",
-						"(SomeTest|OtherTest)",
+						"-skip",
+						"^(Test)?(Slow)$|^(Test)?(Flaky)$/^(some_case)$",
+						"-coverprofile=(TMP_FILE)",
 					},
 				}},
 				WantData: &command.Data{Values: map[string]interface{}{
 					pathArgs.Name():        []string{"."},
-					minCoverageFlag.Name(): 0.0,
-					funcFilterFlag.Name():  []string{"SomeTest", "OtherTest"},
+					minCoverageFlag.Name(): 20.0,
+					skipFlag.Name():        []string{"Slow", "Flaky/some case"},
 					"COVERAGE": map[string]*packageResult{
 						"p1": {
-							noTestFiles,
-							0.0,
-							noTestLine("p1"),
+							TestResult: testSuccess,
+							Coverage:   25.0,
+							Line:       successOutput("p1", 25),
 						},
 					},
 				}},
 			},
 		},
 		{
-			name: "Fails if func-filter flag and min coverage flag",
+			name: "Fails if diff-base and package-coverage",
 			etc: &commandtest.ExecuteTestCase{
-				Args:       []string{"-m", "12.34", "-f", "SomeTest", "OtherTest"},
-				WantStderr: "Cannot set func-filter and min coverage flags simultaneously\n",
-				WantErr:    fmt.Errorf("Cannot set func-filter and min coverage flags simultaneously"),
+				Args:       []string{"-d", "main", "-c", "./...=50"},
+				WantErr:    fmt.Errorf("Cannot set diff-base and package-coverage flags simultaneously"),
+				WantStderr: "Cannot set diff-base and package-coverage flags simultaneously\n",
 				WantData: &command.Data{Values: map[string]interface{}{
-					pathArgs.Name():        []string{"."},
-					minCoverageFlag.Name(): 12.34,
-					funcFilterFlag.Name():  []string{"SomeTest", "OtherTest"},
+					pathArgs.Name():         []string{"."},
+					minCoverageFlag.Name():  0.0,
+					diffBaseFlag.Name():     "main",
+					coverageRuleFlag.Name(): []string{"./...=50"},
 				}},
 			},
 		},
 		{
-			name:       "Fails if tmp file error",
-			tmpFileErr: fmt.Errorf("oops"),
+			name: "Fails if cross-package and coverpkg",
 			etc: &commandtest.ExecuteTestCase{
-				WantStderr: "failed to create temporary file: oops\n",
-				WantErr:    fmt.Errorf("failed to create temporary file: oops"),
+				Args:       []string{"-x", "--coverpkg", "./..."},
+				WantErr:    fmt.Errorf("Cannot set cross-package and coverpkg flags simultaneously"),
+				WantStderr: "Cannot set cross-package and coverpkg flags simultaneously\n",
 				WantData: &command.Data{Values: map[string]interface{}{
-					pathArgs.Name():        []string{"."},
-					minCoverageFlag.Name(): 0.0,
+					pathArgs.Name():         []string{"."},
+					minCoverageFlag.Name():  0.0,
+					crossPackageFlag.Name(): true,
+					coverPkgFlag.Name():     "./...",
 				}},
 			},
 		},
 		{
-			name: "Succeeds if coverage result is above threshold",
+			name:           "Excludes files from coverage",
+			recordsHistory: true,
+			coverProfile: profileLines("set",
+				profileBlocks("p1", 1, 4),
+				[]string{"p1/file.pb.go:1.2,1.10 4 0"},
+			),
 			etc: &commandtest.ExecuteTestCase{
-				Args: []string{"-m", "54.32"},
+				Args: []string{"-e", "*.pb.go", "-m", "25"},
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						successOutput("p1", 54.33),
-					},
+					Stdout: successEvents("p1", 12.5),
 				}},
+				WantStdout: stdoutLines(
+					successOutput("p1", 12.5),
+					"Excluded 4 statements from coverage",
+				),
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
+						"-json",
 						".",
 						"-coverprofile=(TMP_FILE)",
 					},
 				}},
 				WantData: &command.Data{Values: map[string]interface{}{
 					pathArgs.Name():        []string{"."},
-					minCoverageFlag.Name(): 54.32,
+					minCoverageFlag.Name(): 25.0,
+					excludeFlag.Name():     []string{"*.pb.go"},
 					"COVERAGE": map[string]*packageResult{
 						"p1": {
-							testSuccess,
-							54.33,
-							successOutput("p1", 54.33),
+							TestResult: testSuccess,
+							Coverage:   25,
+							Line:       successOutput("p1", 12.5),
 						},
 					},
 				}},
 			},
 		},
 		{
-			name: "Succeeds if coverage result is at threshold",
+			name: "Fails if invalid exclude pattern",
+			etc: &commandtest.ExecuteTestCase{
+				Args:       []string{"-e", "["},
+				WantErr:    fmt.Errorf(`invalid exclude pattern "[": syntax error in pattern`),
+				WantStderr: "invalid exclude pattern \"[\": syntax error in pattern\n",
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+					excludeFlag.Name():     []string{"["},
+				}},
+			},
+		},
+		{
+			name: "Fails if exported function has no coverage",
+			coverProfile: profileLines("set", []string{
+				testCoverFile + ":3.26,4.7 1 0",
+				testCoverFile + ":12.26,14.2 1 1",
+			}),
 			etc: &commandtest.ExecuteTestCase{
-				Args: []string{"-m", "54.32"},
+				Args: []string{"--require-exported"},
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						successOutput("p1", 54.32),
-					},
+					Stdout: coverNoTestEvents(testModulePath + "/testdata/cover"),
 				}},
+				WantStdout: stdoutLines(coverNoTestLine(testModulePath + "/testdata/cover")),
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
+						"-json",
 						".",
 						"-coverprofile=(TMP_FILE)",
 					},
 				}},
+				WantErr: fmt.Errorf("1 exported function(s) have no coverage"),
+				WantStderr: stdoutLines(
+					"Exported functions without coverage:",
+					"  testdata/cover/cover.go:3 Covered",
+					"1 exported function(s) have no coverage",
+				),
 				WantData: &command.Data{Values: map[string]interface{}{
 					pathArgs.Name():        []string{"."},
-					minCoverageFlag.Name(): 54.32,
+					minCoverageFlag.Name(): 0.0,
+					requireExpFlag.Name():  true,
 					"COVERAGE": map[string]*packageResult{
-						"p1": {
-							testSuccess,
-							54.32,
-							successOutput("p1", 54.32),
+						testModulePath + "/testdata/cover": {
+							TestResult: noTestFiles,
+							Coverage:   50,
+							Line:       coverNoTestLine(testModulePath + "/testdata/cover"),
 						},
 					},
 				}},
 			},
 		},
 		{
-			name: "Fails if coverage result is below threshold",
+			name:           "Prints uncovered lines",
+			recordsHistory: true,
+			coverProfile:   profileLines("set", profileBlocks("p1", 1, 3)),
 			etc: &commandtest.ExecuteTestCase{
-				Args: []string{"-m", "54.32"},
+				Args: []string{"-u"},
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						successOutput("p1", 54.31),
-					},
+					Stdout: successEvents("p1", 33.33),
 				}},
+				WantStdout: stdoutLines(
+					successOutput("p1", 33.33),
+					"Uncovered lines:",
+					"  p1/file.go (2 uncovered statements)",
+					"    2-3",
+				),
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
+						"-json",
 						".",
 						"-coverprofile=(TMP_FILE)",
 					},
 				}},
-				WantErr:    fmt.Errorf("Coverage of package \"p1\" (54.3%%) must be at least 54.3%%"),
-				WantStderr: "Coverage of package \"p1\" (54.3%) must be at least 54.3%\n",
 				WantData: &command.Data{Values: map[string]interface{}{
 					pathArgs.Name():        []string{"."},
-					minCoverageFlag.Name(): 54.32,
+					minCoverageFlag.Name(): 0.0,
+					uncoveredFlag.Name():   true,
 					"COVERAGE": map[string]*packageResult{
 						"p1": {
-							testSuccess,
-							54.31,
-							successOutput("p1", 54.31),
+							TestResult: testSuccess,
+							Coverage:   100.0 / 3,
+							Line:       successOutput("p1", 33.33),
 						},
 					},
 				}},
 			},
 		},
 		{
-			name: "Fails if multiple regexes for same package",
+			name: "Fails if multiple results for same package",
 			etc: &commandtest.ExecuteTestCase{
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						noTestLine("p1"),
-						successOutput("p1", 12.34),
-					},
+					Stdout: concat(
+						noTestEvents("p1"),
+						successEvents("p1", 12.5),
+					),
 				}},
-				WantErr:    fmt.Errorf("event handling error: Multiple results for package \"p1\":\n  Result 1: ?       p1        [no test files]\n  Result 2: ok \t p1 \t 0.123s \t coverage: \t 12.34%% of statements"),
-				WantStderr: "event handling error: Multiple results for package \"p1\":\n  Result 1: ?       p1        [no test files]\n  Result 2: ok \t p1 \t 0.123s \t coverage: \t 12.34% of statements\n",
+				WantStdout: stdoutLines(
+					noTestLine("p1"),
+					successOutput("p1", 12.5),
+				),
+				WantErr:    fmt.Errorf("event handling error: Multiple results for package \"p1\":\n  Result 1: ?   \tp1\t[no test files]\n  Result 2: ok  \tp1\t0.123s\tcoverage: 12.50%% of statements"),
+				WantStderr: "event handling error: Multiple results for package \"p1\":\n  Result 1: ?   \tp1\t[no test files]\n  Result 2: ok  \tp1\t0.123s\tcoverage: 12.50% of statements\n",
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
+						"-json",
 						".",
 						"-coverprofile=(TMP_FILE)",
 					},
@@ -431,18 +1474,24 @@ func TestExecute(t *testing.T) {
 			name: "Handles multiple errors",
 			etc: &commandtest.ExecuteTestCase{
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						failLine("p1"),
-						noTestLine("p1"),
-						successOutput("p1", 12.34),
-					},
+					Stdout: concat(
+						failEvents("p1"),
+						noTestEvents("p1"),
+						successEvents("p1", 12.5),
+					),
 				}},
-				WantErr:    fmt.Errorf("event handling error: Multiple results for package \"p1\":\n  Result 1: FAIL \t p1 \t abc \t 123 def\n  Result 2: ?       p1        [no test files]"),
-				WantStderr: "event handling error: Multiple results for package \"p1\":\n  Result 1: FAIL \t p1 \t abc \t 123 def\n  Result 2: ?       p1        [no test files]\n",
+				WantStdout: stdoutLines(
+					"FAIL",
+					failLine("p1"),
+					noTestLine("p1"),
+				),
+				WantErr:    fmt.Errorf("event handling error: Multiple results for package \"p1\":\n  Result 1: FAIL\tp1\t0.123s\n  Result 2: ?   \tp1\t[no test files]"),
+				WantStderr: "event handling error: Multiple results for package \"p1\":\n  Result 1: FAIL\tp1\t0.123s\n  Result 2: ?   \tp1\t[no test files]\n",
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
+						"-json",
 						".",
 						"-coverprofile=(TMP_FILE)",
 					},
@@ -454,20 +1503,29 @@ func TestExecute(t *testing.T) {
 			},
 		},
 		{
-			name: "Handles multiple pacakges successes",
+			name:           "Handles multiple pacakges successes",
+			recordsHistory: true,
+			coverProfile:   profileLines("set", profileBlocks("p1", 1, 8), profileBlocks("p4", 3, 4)),
 			etc: &commandtest.ExecuteTestCase{
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						successOutput("p1", 12.34),
-						noTestLine("p2"),
-						noTestLine("p3"),
-						successOutput("p4", 98.76),
-					},
+					Stdout: concat(
+						successEvents("p1", 12.5),
+						noTestEvents("p2"),
+						noTestEvents("p3"),
+						successEvents("p4", 75.0),
+					),
 				}},
+				WantStdout: stdoutLines(
+					successOutput("p1", 12.5),
+					noTestLine("p2"),
+					noTestLine("p3"),
+					successOutput("p4", 75.0),
+				),
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
+						"-json",
 						".",
 						"-coverprofile=(TMP_FILE)",
 					},
@@ -477,48 +1535,57 @@ func TestExecute(t *testing.T) {
 					minCoverageFlag.Name(): 0.0,
 					"COVERAGE": map[string]*packageResult{
 						"p1": {
-							testSuccess,
-							12.34,
-							successOutput("p1", 12.34),
+							TestResult: testSuccess,
+							Coverage:   12.5,
+							Line:       successOutput("p1", 12.5),
 						},
 						"p2": {
-							noTestFiles,
-							0.0,
-							noTestLine("p2"),
+							TestResult: noTestFiles,
+							Coverage:   0.0,
+							Line:       noTestLine("p2"),
 						},
 						"p3": {
-							noTestFiles,
-							0.0,
-							noTestLine("p3"),
+							TestResult: noTestFiles,
+							Coverage:   0.0,
+							Line:       noTestLine("p3"),
 						},
 						"p4": {
-							testSuccess,
-							98.76,
-							successOutput("p4", 98.76),
+							TestResult: testSuccess,
+							Coverage:   75.0,
+							Line:       successOutput("p4", 75.0),
 						},
 					},
 				}},
 			},
 		},
 		{
-			name: "Handles multiple pacakges successes with package count flag",
+			name:           "Handles multiple pacakges successes with package count flag",
+			recordsHistory: true,
+			coverProfile:   profileLines("set", profileBlocks("p1", 1, 8), profileBlocks("p4", 3, 4)),
 			etc: &commandtest.ExecuteTestCase{
 				Args: []string{
 					"--package-count",
 					"4",
 				},
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						successOutput("p1", 12.34),
-						noTestLine("p2"),
-						noTestLine("p3"),
-						successOutput("p4", 98.76),
-					},
+					Stdout: concat(
+						successEvents("p1", 12.5),
+						noTestEvents("p2"),
+						noTestEvents("p3"),
+						successEvents("p4", 75.0),
+					),
 				}},
+				WantStdout: stdoutLines(
+					successOutput("p1", 12.5),
+					noTestLine("p2"),
+					noTestLine("p3"),
+					successOutput("p4", 75.0),
+				),
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
+						"-json",
 						".",
 						"-coverprofile=(TMP_FILE)",
 					},
@@ -529,24 +1596,66 @@ func TestExecute(t *testing.T) {
 					minCoverageFlag.Name():  0.0,
 					"COVERAGE": map[string]*packageResult{
 						"p1": {
-							testSuccess,
-							12.34,
-							successOutput("p1", 12.34),
+							TestResult: testSuccess,
+							Coverage:   12.5,
+							Line:       successOutput("p1", 12.5),
 						},
 						"p2": {
-							noTestFiles,
-							0.0,
-							noTestLine("p2"),
+							TestResult: noTestFiles,
+							Coverage:   0.0,
+							Line:       noTestLine("p2"),
 						},
 						"p3": {
-							noTestFiles,
-							0.0,
-							noTestLine("p3"),
+							TestResult: noTestFiles,
+							Coverage:   0.0,
+							Line:       noTestLine("p3"),
 						},
 						"p4": {
-							testSuccess,
-							98.76,
-							successOutput("p4", 98.76),
+							TestResult: testSuccess,
+							Coverage:   75.0,
+							Line:       successOutput("p4", 75.0),
+						},
+					},
+				}},
+			},
+		},
+		{
+			name:           "Doesn't check coverage of packages without test files",
+			recordsHistory: true,
+			coverProfile:   profileLines("set", profileBlocks("p1", 3, 4), profileBlocks(testModulePath+"/testdata/cover", 0, 4)),
+			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"-m", "50"},
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: concat(
+						successEvents("p1", 75),
+						coverNoTestEvents(testModulePath+"/testdata/cover"),
+					),
+				}},
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantStdout: stdoutLines(
+					successOutput("p1", 75),
+					coverNoTestLine(testModulePath+"/testdata/cover"),
+				),
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 50.0,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: testSuccess,
+							Coverage:   75,
+							Line:       successOutput("p1", 75),
+						},
+						testModulePath + "/testdata/cover": {
+							TestResult: noTestFiles,
+							Line:       coverNoTestLine(testModulePath + "/testdata/cover"),
 						},
 					},
 				}},
@@ -560,21 +1669,28 @@ func TestExecute(t *testing.T) {
 					"5",
 				},
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						successOutput("p1", 12.34),
-						noTestLine("p2"),
-						noTestLine("p3"),
-						successOutput("p4", 98.76),
-					},
+					Stdout: concat(
+						successEvents("p1", 12.5),
+						noTestEvents("p2"),
+						noTestEvents("p3"),
+						successEvents("p4", 75.0),
+					),
 				}},
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
+						"-json",
 						".",
 						"-coverprofile=(TMP_FILE)",
 					},
 				}},
+				WantStdout: stdoutLines(
+					successOutput("p1", 12.5),
+					noTestLine("p2"),
+					noTestLine("p3"),
+					successOutput("p4", 75.0),
+				),
 				WantStderr: strings.Join([]string{
 					"Expected 5 packages, got 4:",
 					"p1",
@@ -592,24 +1708,36 @@ func TestExecute(t *testing.T) {
 			},
 		},
 		{
-			name: "Handles multiple pacakges with errors",
+			name:         "Handles multiple pacakges with errors",
+			coverProfile: profileLines("set", profileBlocks("p1", 1, 8), profileBlocks("p6", 3, 4)),
 			etc: &commandtest.ExecuteTestCase{
 				RunResponses: []*commandtest.FakeRun{{
-					Stdout: []string{
-						successOutput("p1", 12.34),
-						noTestLine("p2"),
-						failLine("p3"),
-						noTestLine("p4"),
-						failLine("p5"),
-						successOutput("p6", 98.76),
-					},
+					Stdout: concat(
+						successEvents("p1", 12.5),
+						noTestEvents("p2"),
+						failEvents("p3"),
+						noTestEvents("p4"),
+						failEvents("p5"),
+						successEvents("p6", 75.0),
+					),
 				}},
+				WantStdout: stdoutLines(
+					successOutput("p1", 12.5),
+					noTestLine("p2"),
+					"FAIL",
+					failLine("p3"),
+					noTestLine("p4"),
+					"FAIL",
+					failLine("p5"),
+					successOutput("p6", 75.0),
+				),
 				WantStderr: "Tests failed for package: p3\nTests failed for package: p5\n",
 				WantErr:    fmt.Errorf("Tests failed for package: p5"),
 				WantRunContents: []*commandtest.RunContents{{
 					Name: "go",
 					Args: []string{
 						"test",
+						"-json",
 						".",
 						"-coverprofile=(TMP_FILE)",
 					},
@@ -619,39 +1747,95 @@ func TestExecute(t *testing.T) {
 					minCoverageFlag.Name(): 0.0,
 					"COVERAGE": map[string]*packageResult{
 						"p1": {
-							testSuccess,
-							12.34,
-							successOutput("p1", 12.34),
+							TestResult: testSuccess,
+							Coverage:   12.5,
+							Line:       successOutput("p1", 12.5),
 						},
 						"p2": {
-							noTestFiles,
-							0.0,
-							noTestLine("p2"),
+							TestResult: noTestFiles,
+							Coverage:   0.0,
+							Line:       noTestLine("p2"),
 						},
 						"p3": {
-							testFailure,
-							0.0,
-							failLine("p3"),
+							TestResult: testFailure,
+							Coverage:   0.0,
+							Line:       failLine("p3"),
 						},
 						"p4": {
-							noTestFiles,
-							0.0,
-							noTestLine("p4"),
+							TestResult: noTestFiles,
+							Coverage:   0.0,
+							Line:       noTestLine("p4"),
 						},
 						"p5": {
-							testFailure,
-							0.0,
-							failLine("p5"),
+							TestResult: testFailure,
+							Coverage:   0.0,
+							Line:       failLine("p5"),
 						},
 						"p6": {
-							testSuccess,
-							98.76,
-							successOutput("p6", 98.76),
+							TestResult: testSuccess,
+							Coverage:   75.0,
+							Line:       successOutput("p6", 75.0),
+						},
+					},
+				}},
+			},
+		},
+		{
+			name:         "Ignores coverage threshold for packages without statements",
+			coverProfile: profileLines("set", profileBlocks("p1", 0, 0)),
+			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"-m", "54"},
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: successEvents("p1", 0),
+				}},
+				WantStdout: stdoutLines(successOutput("p1", 0)),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 54.0,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: testSuccess,
+							Coverage:   0.0,
+							Line:       successOutput("p1", 0),
 						},
 					},
 				}},
 			},
 		},
+		{
+			name:         "Fails if invalid coverage profile",
+			coverProfile: []string{"p1/file.go:1.1,2.2 1 1"},
+			etc: &commandtest.ExecuteTestCase{
+				RunResponses: []*commandtest.FakeRun{{
+					Stdout: successEvents("p1", 100),
+				}},
+				WantStdout: stdoutLines(successOutput("p1", 100)),
+				WantRunContents: []*commandtest.RunContents{{
+					Name: "go",
+					Args: []string{
+						"test",
+						"-json",
+						".",
+						"-coverprofile=(TMP_FILE)",
+					},
+				}},
+				WantErr:    fmt.Errorf(`failed to read coverage profile: coverage profile line 1: expected mode line, got "p1/file.go:1.1,2.2 1 1"`),
+				WantStderr: "failed to read coverage profile: coverage profile line 1: expected mode line, got \"p1/file.go:1.1,2.2 1 1\"\n",
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():        []string{"."},
+					minCoverageFlag.Name(): 0.0,
+				}},
+			},
+		},
 		/* Useful for commenting out tests. */
 	} {
 		t.Run(test.name, func(t *testing.T) {
@@ -659,27 +1843,90 @@ func TestExecute(t *testing.T) {
 			if err != nil {
 				t.Fatalf("failed to create temporary file")
 			}
+			t.Cleanup(func() {
+				tmp.Close()
+				os.Remove(tmp.Name())
+			})
 			commandtest.StubValue(t, &tmpFile, func() (*os.File, error) {
 				return tmp, test.tmpFileErr
 			})
+			if err := os.WriteFile(tmp.Name(), []byte(strings.Join(test.coverProfile, "\n")), 0644); err != nil {
+				t.Fatalf("failed to write coverage profile: %v", err)
+			}
 
+			var usesTmpFile bool
 			for _, rc := range test.etc.WantRunContents {
 				for i, a := range rc.Args {
 					if a == "-coverprofile=(TMP_FILE)" {
 						rc.Args[i] = fmt.Sprintf("-coverprofile=%s", tmp.Name())
+						usesTmpFile = true
 					}
 				}
 			}
 
-			test.etc.Node = CLI().Node()
-			if test.etc.RunResponses != nil && len(test.etc.RunResponses[0].Stdout) > 0 {
-				test.etc.WantStdout = fmt.Sprintf("%s%s", test.etc.WantStdout, strings.Join(test.etc.RunResponses[0].Stdout, "\n")) + "\n"
+			if test.recordsHistory {
+				test.etc.RunResponses = append(test.etc.RunResponses, gitStatusRun("head"))
+				test.etc.WantRunContents = append(test.etc.WantRunContents, gitStatusContents(t))
 			}
+
+			test.etc.Node = CLI().Node()
 			commandertest.ExecuteTest(t, test.etc)
+
+			if _, err := os.Stat(tmp.Name()); usesTmpFile && !os.IsNotExist(err) {
+				t.Errorf("Execute(%v) didn't remove the temporary coverage profile (stat error: %v)", test.etc.Args, err)
+			}
 		})
 	}
 }
 
+func TestCoverProfileFlag(t *testing.T) {
+	coverProfile := filepath.Join(t.TempDir(), "cover.out")
+	commandtest.StubValue(t, &tmpFile, func() (*os.File, error) {
+		t.Fatalf("tmpFile() should not be called when the coverprofile flag is provided")
+		return nil, nil
+	})
+
+	commandertest.ExecuteTest(t, &commandtest.ExecuteTestCase{
+		Node: CLI().Node(),
+		Args: []string{"--coverprofile", coverProfile},
+		RunResponses: []*commandtest.FakeRun{{
+			Stdout: successEvents("p1", 50),
+			F: func(t *testing.T) {
+				if err := os.WriteFile(coverProfile, []byte(strings.Join(profileLines("set", profileBlocks("p1", 1, 2)), "\n")), 0644); err != nil {
+					t.Fatalf("failed to write coverage profile: %v", err)
+				}
+			},
+		}, gitStatusRun("head")},
+		WantStdout: stdoutLines(successOutput("p1", 50)),
+		WantRunContents: []*commandtest.RunContents{{
+			Name: "go",
+			Args: []string{
+				"test",
+				"-json",
+				".",
+				fmt.Sprintf("-coverprofile=%s", coverProfile),
+			},
+		}, gitStatusContents(t)},
+		WantData: &command.Data{Values: map[string]interface{}{
+			pathArgs.Name():         []string{"."},
+			minCoverageFlag.Name():  0.0,
+			coverProfileFlag.Name(): coverProfile,
+			"COVERPROFILE":          coverProfile,
+			"COVERAGE": map[string]*packageResult{
+				"p1": {
+					TestResult: testSuccess,
+					Coverage:   50,
+					Line:       successOutput("p1", 50),
+				},
+			},
+		}},
+	})
+
+	if _, err := os.Stat(coverProfile); err != nil {
+		t.Errorf("coverage profile wasn't kept: %v", err)
+	}
+}
+
 func TestAutocomplete(t *testing.T) {
 	for _, test := range []struct {
 		name string
@@ -699,8 +1946,7 @@ func TestAutocomplete(t *testing.T) {
 				},
 				WantData: &command.Data{
 					Values: map[string]interface{}{
-						pathArgs.Name():        []string{""},
-						minCoverageFlag.Name(): 0.0,
+						pathArgs.Name(): []string{""},
 					},
 				},
 			},
@@ -712,18 +1958,95 @@ func TestAutocomplete(t *testing.T) {
 				Want: &command.Autocompletion{
 					Suggestions: []string{
 						"Autocomplete",
+						"BadgeColor",
+						"CheckExportedFuncs",
+						"CheckRegressions",
+						"ClassifyFailures",
+						"CoberturaReport",
+						"ComputeCoverage",
+						"ComputeDiffCoverage",
+						"ConfigDefaults",
+						"CoverProfileFlag",
+						"CoverageHistory",
+						"CoveragePercent",
+						"CoverageRuleFor",
+						"Dedent",
+						"DiffBaseFlag",
+						"DiscoverTests",
+						"EventHandler",
+						"ExactTestsPattern",
+						"ExcludeCoverage",
 						"Execute",
+						"FailedFlag",
+						"FailingTests",
+						"FailureMessage",
+						"FindConfigFile",
+						"FindGoModule",
+						"FindTestPackages",
+						"FindTestPackagesErrors",
+						"FuncFilterPattern",
+						"GitHeadCommit",
+						"ImportPath",
+						"IsTestName",
+						"JSONOutFlag",
+						"JSONReport",
+						"JUnitFlag",
+						"JUnitReport",
+						"LCOVReport",
+						"LastFailuresUpdate",
+						"LineClass",
+						"List",
+						"MangleSubtestName",
+						"Merge",
+						"MergeCoverProfiles",
 						"Metadata",
+						"NoRegressFlag",
+						"PackageTests",
+						"ParseCoverProfile",
+						"ParseCoverageRules",
+						"ParseGitDiff",
+						"PrintFuncCoverage",
+						"PrintUncovered",
+						"ReadConfig",
+						"ReadModulePath",
+						"RelativePath",
+						"ReportsWrittenOnPackageCountMismatch",
+						"SkipPattern",
+						"SourcePath",
+						"UncoveredRanges",
+						"UntrackedFiles",
+						"ValidateExcludePatterns",
+						"WriteCoverageBadge",
+						"WriteHTMLReport",
 					},
 				},
 				WantData: &command.Data{
 					Values: map[string]interface{}{
-						pathArgs.Name():       []string{"."},
 						funcFilterFlag.Name(): []string{""},
 					},
 				},
 			},
 		},
+		{
+			name: "completes subtest names",
+			ctc: &commandtest.CompleteTestCase{
+				Args: "cmd -f Merge/",
+				Want: &command.Autocompletion{
+					Suggestions: []string{
+						"Merge/checks_coverage_thresholds",
+						"Merge/doesn't_check_packages_without_test_files",
+						"Merge/fails_if_coverage_is_too_low",
+						"Merge/fails_if_profile_doesn't_exist",
+						"Merge/merges_profiles",
+					},
+				},
+				WantData: &command.Data{
+					Values: map[string]interface{}{
+						funcFilterFlag.Name(): []string{"Merge/"},
+					},
+				},
+			},
+		},
 		{
 			name: "completes test function names in all sub directories",
 			ctc: &commandtest.CompleteTestCase{
@@ -731,11 +2054,69 @@ func TestAutocomplete(t *testing.T) {
 				Want: &command.Autocompletion{
 					Suggestions: []string{
 						"Autocomplete",
+						"BadgeColor",
+						"CheckExportedFuncs",
+						"CheckRegressions",
+						"ClassifyFailures",
+						"CoberturaReport",
+						"ComputeCoverage",
+						"ComputeDiffCoverage",
+						"ConfigDefaults",
+						"CoverProfileFlag",
+						"CoverageHistory",
+						"CoveragePercent",
+						"CoverageRuleFor",
+						"Dedent",
+						"DiffBaseFlag",
+						"DiscoverTests",
+						"EventHandler",
+						"ExactTestsPattern",
+						"ExcludeCoverage",
 						"Execute",
+						"FailedFlag",
+						"FailingTests",
+						"FailureMessage",
+						"FindConfigFile",
+						"FindGoModule",
+						"FindTestPackages",
+						"FindTestPackagesErrors",
+						"FuncFilterPattern",
+						"GitHeadCommit",
+						"ImportPath",
+						"IsTestName",
+						"JSONOutFlag",
+						"JSONReport",
+						"JUnitFlag",
+						"JUnitReport",
+						"LCOVReport",
+						"LastFailuresUpdate",
+						"LineClass",
+						"List",
+						"MangleSubtestName",
+						"Merge",
+						"MergeCoverProfiles",
 						"Metadata",
+						"NoRegressFlag",
 						"Other",
+						"PackageTests",
+						"ParseCoverProfile",
+						"ParseCoverageRules",
+						"ParseGitDiff",
+						"PrintFuncCoverage",
+						"PrintUncovered",
+						"ReadConfig",
+						"ReadModulePath",
+						"RelativePath",
+						"ReportsWrittenOnPackageCountMismatch",
+						"SkipPattern",
+						"SourcePath",
 						"That",
 						"This",
+						"UncoveredRanges",
+						"UntrackedFiles",
+						"ValidateExcludePatterns",
+						"WriteCoverageBadge",
+						"WriteHTMLReport",
 					},
 				},
 				WantData: &command.Data{
@@ -757,7 +2138,6 @@ func TestAutocomplete(t *testing.T) {
 				},
 				WantData: &command.Data{
 					Values: map[string]interface{}{
-						pathArgs.Name():       []string{"."},
 						funcFilterFlag.Name(): []string{"A"},
 					},
 				},
@@ -780,6 +2160,22 @@ func TestAutocomplete(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "completes skipped test function names",
+			ctc: &commandtest.CompleteTestCase{
+				Args: "cmd --skip A",
+				Want: &command.Autocompletion{
+					Suggestions: []string{
+						"Autocomplete",
+					},
+				},
+				WantData: &command.Data{
+					Values: map[string]interface{}{
+						skipFlag.Name(): []string{"A"},
+					},
+				},
+			},
+		},
 		/* Useful for commenting out tests. */
 	} {
 		t.Run(test.name, func(t *testing.T) {
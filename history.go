@@ -0,0 +1,197 @@
+package gocli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/leep-frog/command/command"
+	"github.com/leep-frog/command/commander"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// maxHistoryEntries is the number of commits that are kept in a history file.
+const maxHistoryEntries = 100
+
+var (
+	// userCacheDir is a variable so it can be stubbed in tests.
+	userCacheDir = os.UserCacheDir
+)
+
+// coverageHistory is the per-package coverage recorded for a module.
+type coverageHistory struct {
+	// Entries are the recorded runs, ordered from oldest to newest. There is at
+	// most one entry per commit.
+	Entries []*historyEntry `json:"entries"`
+}
+
+// historyEntry is the per-package coverage of a successful run.
+type historyEntry struct {
+	Commit string    `json:"commit"`
+	Time   time.Time `json:"time"`
+	// Packages is a map from package import path to coverage percentage.
+	Packages map[string]float64 `json:"packages"`
+}
+
+// historyStore is the coverage history for a module at a specific commit.
+type historyStore struct {
+	filename string
+	commit   string
+	// dirty is whether the working tree has changes that aren't committed.
+	dirty   bool
+	history *coverageHistory
+}
+
+// gitHeadCommit returns the commit that is checked out in the provided
+// directory and whether the working tree has changes that aren't committed.
+func gitHeadCommit(o command.Output, d *command.Data, dir string) (string, bool, error) {
+	out, err := (&commander.ShellCommand[[]string]{
+		CommandName: "git",
+		Args:        []string{"status", "--porcelain=v2", "--branch"},
+		Dir:         dir,
+		HideStderr:  true,
+	}).Run(o, d)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get git commit: %v", err)
+	}
+	var commit string
+	var dirty bool
+	for _, line := range out {
+		if c, ok := strings.CutPrefix(line, "# branch.oid "); ok {
+			commit = strings.TrimSpace(c)
+		} else if line != "" && !strings.HasPrefix(line, "#") {
+			dirty = true
+		}
+	}
+	// The commit is "(initial)" if nothing has been committed yet.
+	if commit == "" || commit == "(initial)" {
+		return "", false, fmt.Errorf("failed to get git commit: no commit is checked out")
+	}
+	return commit, dirty, nil
+}
+
+// openHistory reads the coverage history for the provided module (at the
+// commit that is currently checked out) from the user cache directory.
+func openHistory(o command.Output, d *command.Data, mod *goModule) (*historyStore, error) {
+	if mod == nil {
+		return nil, fmt.Errorf("coverage history can only be used inside of a go module")
+	}
+	commit, dirty, err := gitHeadCommit(o, d, mod.Dir)
+	if err != nil {
+		return nil, err
+	}
+	hs, err := loadHistory(mod, commit)
+	if err != nil {
+		return nil, err
+	}
+	hs.dirty = dirty
+	return hs, nil
+}
+
+// loadHistory reads the coverage history for the provided module and commit
+// from the user cache directory.
+func loadHistory(mod *goModule, commit string) (*historyStore, error) {
+	cacheDir, err := userCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user cache directory: %v", err)
+	}
+
+	hs := &historyStore{
+		filename: filepath.Join(cacheDir, "gocli", "history", url.PathEscape(mod.Path)+".json"),
+		commit:   commit,
+		history:  &coverageHistory{},
+	}
+	b, err := os.ReadFile(hs.filename)
+	if os.IsNotExist(err) {
+		return hs, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read history file: %v", err)
+	}
+	if err := json.Unmarshal(b, hs.history); err != nil {
+		return nil, fmt.Errorf("failed to parse history file %s: %v", hs.filename, err)
+	}
+	return hs, nil
+}
+
+// baseline returns the most recent entry for a commit other than the current
+// one (or nil if there isn't one).
+func (hs *historyStore) baseline() *historyEntry {
+	for i := len(hs.history.Entries) - 1; i >= 0; i-- {
+		if e := hs.history.Entries[i]; e.Commit != hs.commit {
+			return e
+		}
+	}
+	return nil
+}
+
+// record saves the provided coverage for the current commit. Coverage that
+// was previously recorded for the commit is kept for packages that aren't in
+// the provided coverage (so running a subset of packages doesn't drop the
+// rest of the commit's packages from the baseline).
+func (hs *historyStore) record(coverage map[string]float64) error {
+	packages := map[string]float64{}
+	entries := slices.DeleteFunc(hs.history.Entries, func(e *historyEntry) bool {
+		if e.Commit != hs.commit {
+			return false
+		}
+		maps.Copy(packages, e.Packages)
+		return true
+	})
+	maps.Copy(packages, coverage)
+	entries = append(entries, &historyEntry{
+		Commit:   hs.commit,
+		Time:     timeNow().UTC(),
+		Packages: packages,
+	})
+	if len(entries) > maxHistoryEntries {
+		entries = entries[len(entries)-maxHistoryEntries:]
+	}
+	hs.history.Entries = entries
+
+	b, err := json.MarshalIndent(hs.history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(hs.filename), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %v", err)
+	}
+	return os.WriteFile(hs.filename, append(b, '\n'), 0644)
+}
+
+// shortCommit returns the abbreviated form of a commit hash.
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// checkRegressions returns an error if the coverage of any of the provided
+// packages dropped by more than the tolerance (in percentage points) from the
+// baseline.
+func checkRegressions(o command.Output, coverage map[string]float64, baseline *historyEntry, tolerance float64) error {
+	if baseline == nil {
+		o.Stdoutln("No coverage history to check for regressions")
+		return nil
+	}
+
+	packages := maps.Keys(coverage)
+	slices.Sort(packages)
+
+	var retErr error
+	for _, p := range packages {
+		prev, ok := baseline.Packages[p]
+		if !ok {
+			continue
+		}
+		if prev-coverage[p] > tolerance {
+			retErr = o.Stderrf("Coverage of package %q dropped from %s to %s (commit %s, tolerance: %s)\n", p, percentFormat(prev), percentFormat(coverage[p]), shortCommit(baseline.Commit), percentFormat(tolerance))
+		}
+	}
+	return retErr
+}
@@ -0,0 +1,319 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/leep-frog/command/command"
+	"github.com/leep-frog/command/commander"
+	"github.com/leep-frog/command/commandertest"
+	"github.com/leep-frog/command/commandtest"
+)
+
+// stubHistory stubs the cache directory used for coverage history and returns
+// the history file path.
+func stubHistory(t *testing.T) string {
+	t.Helper()
+	cacheDir := t.TempDir()
+	commandtest.StubValue(t, &userCacheDir, func() (string, error) { return cacheDir, nil })
+	commandtest.StubValue(t, &timeNow, func() time.Time { return time.Unix(1700000000, 0) })
+	return filepath.Join(cacheDir, "gocli", "history", "github.com%2Fleep-frog%2Fgocli.json")
+}
+
+// gitStatusRun returns the stubbed `git status` output for the provided commit
+// and changed files.
+func gitStatusRun(commit string, changes ...string) *commandtest.FakeRun {
+	return &commandtest.FakeRun{Stdout: append([]string{
+		"# branch.oid " + commit,
+		"# branch.head main",
+	}, changes...)}
+}
+
+// gitStatusContents returns the `git status` command run for coverage history.
+func gitStatusContents(t *testing.T) *commandtest.RunContents {
+	return &commandtest.RunContents{
+		Name: "git",
+		Args: []string{"status", "--porcelain=v2", "--branch"},
+		Dir:  testModule(t).Dir,
+	}
+}
+
+func TestGitHeadCommit(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		run       *commandtest.FakeRun
+		wantDirty bool
+		wantErr   bool
+	}{
+		{
+			name: "gets commit",
+			run:  gitStatusRun("head"),
+		},
+		{
+			name:      "gets commit with changes",
+			run:       gitStatusRun("head", "1 .M N... 100644 100644 100644 abc abc p1/p1.go"),
+			wantDirty: true,
+		},
+		{
+			name:      "untracked files are changes",
+			run:       gitStatusRun("head", "? p1/new.go"),
+			wantDirty: true,
+		},
+		{
+			name:    "fails without commits",
+			run:     gitStatusRun("(initial)"),
+			wantErr: true,
+		},
+		{
+			name:    "fails if git fails",
+			run:     &commandtest.FakeRun{Err: fmt.Errorf("not a git repository")},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var commit string
+			var dirty bool
+			var err error
+			commandertest.ExecuteTest(t, &commandtest.ExecuteTestCase{
+				Node: commander.SerialNodes(&commander.ExecutorProcessor{F: func(o command.Output, d *command.Data) error {
+					commit, dirty, err = gitHeadCommit(o, d, testModule(t).Dir)
+					return nil
+				}}),
+				RunResponses:    []*commandtest.FakeRun{test.run},
+				WantRunContents: []*commandtest.RunContents{gitStatusContents(t)},
+			})
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("gitHeadCommit() returned error %v; want error: %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if commit != "head" || dirty != test.wantDirty {
+				t.Errorf("gitHeadCommit() returned (%q, %v); want (%q, %v)", commit, dirty, "head", test.wantDirty)
+			}
+		})
+	}
+}
+
+func TestCoverageHistory(t *testing.T) {
+	mod := testModule(t)
+	stubHistory(t)
+	hs, err := loadHistory(mod, "c1")
+	if err != nil {
+		t.Fatalf("loadHistory() returned error: %v", err)
+	}
+	if got := hs.baseline(); got != nil {
+		t.Errorf("baseline() of empty history returned %v; want nil", got)
+	}
+	if err := hs.record(map[string]float64{"p1": 50}); err != nil {
+		t.Fatalf("record() returned error: %v", err)
+	}
+
+	// Recording the same commit again updates the entry's packages.
+	for i, r := range []struct {
+		commit   string
+		coverage map[string]float64
+	}{
+		{"c2", map[string]float64{"p1": 60, "p2": 30}},
+		{"c3", map[string]float64{"p1": 61}},
+		{"c2", map[string]float64{"p1": 62}},
+	} {
+		hs, err := loadHistory(mod, r.commit)
+		if err != nil {
+			t.Fatalf("loadHistory() returned error: %v", err)
+		}
+		if err := hs.record(r.coverage); err != nil {
+			t.Fatalf("record(%d) returned error: %v", i, err)
+		}
+	}
+
+	hs, err = loadHistory(mod, "c2")
+	if err != nil {
+		t.Fatalf("loadHistory() returned error: %v", err)
+	}
+	at := time.Unix(1700000000, 0).UTC()
+	want := &coverageHistory{Entries: []*historyEntry{
+		{Commit: "c1", Time: at, Packages: map[string]float64{"p1": 50}},
+		{Commit: "c3", Time: at, Packages: map[string]float64{"p1": 61}},
+		{Commit: "c2", Time: at, Packages: map[string]float64{"p1": 62, "p2": 30}},
+	}}
+	if diff := cmp.Diff(want, hs.history); diff != "" {
+		t.Errorf("loadHistory() returned incorrect history (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want.Entries[1], hs.baseline()); diff != "" {
+		t.Errorf("baseline() returned incorrect entry (-want, +got):\n%s", diff)
+	}
+
+	if _, err := openHistory(nil, nil, nil); err == nil {
+		t.Errorf("openHistory(nil) returned nil error; want error")
+	}
+}
+
+func TestCheckRegressions(t *testing.T) {
+	baseline := &historyEntry{
+		Commit:   "0123456789abcdef",
+		Packages: map[string]float64{"p1": 80, "p2": 50, "p3": 70},
+	}
+	coverage := map[string]float64{"p1": 79, "p2": 60, "p3": 65, "p4": 10}
+
+	for _, test := range []struct {
+		name       string
+		baseline   *historyEntry
+		tolerance  float64
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "passes without baseline",
+			wantStdout: "No coverage history to check for regressions\n",
+		},
+		{
+			name:     "fails if coverage dropped",
+			baseline: baseline,
+			wantStderr: strings.Join([]string{
+				`Coverage of package "p1" dropped from 80.0% to 79.0% (commit 0123456, tolerance: 0.0%)`,
+				`Coverage of package "p3" dropped from 70.0% to 65.0% (commit 0123456, tolerance: 0.0%)`,
+				"",
+			}, "\n"),
+		},
+		{
+			name:       "allows drops within tolerance",
+			baseline:   baseline,
+			tolerance:  2,
+			wantStderr: "Coverage of package \"p3\" dropped from 70.0% to 65.0% (commit 0123456, tolerance: 2.0%)\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			o := commandtest.NewOutput()
+			err := checkRegressions(o, coverage, test.baseline, test.tolerance)
+			o.Close()
+			if gotErr := err != nil; gotErr != (test.wantStderr != "") {
+				t.Errorf("checkRegressions() returned error %v; want error: %v", err, test.wantStderr != "")
+			}
+			if diff := cmp.Diff(test.wantStdout, o.GetStdout()); diff != "" {
+				t.Errorf("checkRegressions() printed incorrect stdout (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.wantStderr, o.GetStderr()); diff != "" {
+				t.Errorf("checkRegressions() printed incorrect stderr (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNoRegressFlag(t *testing.T) {
+	pkg := testModulePath + "/p1"
+	for _, test := range []struct {
+		name      string
+		noRegress bool
+		covered   int
+		skip      []string
+		// changes are the uncommitted changes reported by `git status`.
+		changes    []string
+		wantErr    error
+		wantStderr string
+		// wantRecorded is the coverage recorded for the current commit.
+		wantRecorded map[string]float64
+	}{
+		{
+			name:         "records coverage if it didn't regress",
+			noRegress:    true,
+			covered:      7,
+			wantRecorded: map[string]float64{pkg: 70},
+		},
+		{
+			name:       "fails if coverage regressed",
+			noRegress:  true,
+			covered:    5,
+			wantErr:    fmt.Errorf("Coverage of package %q dropped from 70.0%% to 50.0%% (commit base, tolerance: 10.0%%)", pkg),
+			wantStderr: fmt.Sprintf("Coverage of package %q dropped from 70.0%% to 50.0%% (commit base, tolerance: 10.0%%)\n", pkg),
+		},
+		{
+			name:       "doesn't record coverage if tests were skipped",
+			noRegress:  true,
+			covered:    7,
+			skip:       []string{"Slow"},
+			wantStderr: "Coverage will be partial since skipped tests don't contribute to it\n",
+		},
+		{
+			name:      "doesn't record coverage of uncommitted changes",
+			noRegress: true,
+			covered:   7,
+			changes:   []string{"1 .M N... 100644 100644 100644 abc abc p1/p1.go"},
+		},
+		{
+			name:         "records coverage without no-regress flag",
+			covered:      5,
+			wantRecorded: map[string]float64{pkg: 50},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			historyFile := stubHistory(t)
+			writeFile(t, historyFile, `{"entries": [{"commit": "base", "packages": {"`+pkg+`": 70}}]}`)
+			coverProfile := filepath.Join(t.TempDir(), "cover.out")
+			writeFile(t, coverProfile, strings.Join(profileLines("set", profileBlocks(pkg, test.covered, 10)), "\n"))
+			commandtest.StubValue(t, &tmpFile, func() (*os.File, error) {
+				return os.Open(coverProfile)
+			})
+
+			coverage := float64(test.covered * 10)
+			var args []string
+			goTestArgs := []string{"test", "-json", "."}
+			wantData := map[string]interface{}{
+				pathArgs.Name():        []string{"."},
+				minCoverageFlag.Name(): 0.0,
+				"COVERAGE": map[string]*packageResult{
+					pkg: {
+						TestResult: testSuccess,
+						Coverage:   coverage,
+						Line:       successOutput(pkg, coverage),
+					},
+				},
+			}
+			if test.noRegress {
+				args = append(args, "-r", "--regress-tolerance", "10")
+				wantData[noRegressFlag.Name()] = true
+				wantData[regressTolFlag.Name()] = 10.0
+			}
+			if len(test.skip) > 0 {
+				args = append(args, append([]string{"--skip"}, test.skip...)...)
+				goTestArgs = append(goTestArgs, "-skip", skipPattern(test.skip))
+				wantData[skipFlag.Name()] = test.skip
+			}
+			commandertest.ExecuteTest(t, &commandtest.ExecuteTestCase{
+				Node: CLI().Node(),
+				Args: args,
+				RunResponses: []*commandtest.FakeRun{
+					{Stdout: successEvents(pkg, coverage)},
+					gitStatusRun("head", test.changes...),
+				},
+				WantStdout: stdoutLines(successOutput(pkg, coverage)),
+				WantErr:    test.wantErr,
+				WantStderr: test.wantStderr,
+				WantRunContents: []*commandtest.RunContents{
+					{Name: "go", Args: append(goTestArgs, fmt.Sprintf("-coverprofile=%s", coverProfile))},
+					gitStatusContents(t),
+				},
+				WantData: &command.Data{Values: wantData},
+			})
+
+			hs, err := loadHistory(testModule(t), "head")
+			if err != nil {
+				t.Fatalf("loadHistory() returned error: %v", err)
+			}
+			var got map[string]float64
+			for _, e := range hs.history.Entries {
+				if e.Commit == "head" {
+					got = e.Packages
+				}
+			}
+			if diff := cmp.Diff(test.wantRecorded, got); diff != "" {
+				t.Errorf("gt recorded incorrect coverage (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
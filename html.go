@@ -0,0 +1,223 @@
+package gocli
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+const htmlStyle = `
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 0.2em 0.8em; text-align: left; border-bottom: 1px solid #ddd; }
+td.percent { text-align: right; }
+pre { margin: 0; }
+.source td { border: none; padding: 0 0.8em; font-family: monospace; white-space: pre; }
+.source td.line { color: #888; text-align: right; }
+.covered { background-color: #d4f7d4; }
+.uncovered { background-color: #f7d4d4; }
+.partial { background-color: #f7f0c4; }
+`
+
+var (
+	htmlIndexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage report</title>
+<style>{{.Style}}</style>
+</head>
+<body>
+<h1>Coverage report</h1>
+<p>Total coverage: {{.Percent}} ({{.Covered}}/{{.Statements}} statements)</p>
+{{range .Packages}}
+<h2>{{.Name}}: {{.Percent}}</h2>
+<table>
+<tr><th>File</th><th>Coverage</th><th>Statements</th></tr>
+{{range .Files}}<tr><td><a href="{{.Link}}">{{.Name}}</a></td><td class="percent">{{.Percent}}</td><td class="percent">{{.Covered}}/{{.Statements}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+	htmlFileTemplate = template.Must(template.New("file").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>{{.Style}}</style>
+</head>
+<body>
+<p><a href="{{.IndexLink}}">Index</a></p>
+<h1>{{.Name}}: {{.Percent}}</h1>
+{{if .Funcs}}<table>
+<tr><th>Function</th><th>Coverage</th><th>Statements</th></tr>
+{{range .Funcs}}<tr><td><a href="#L{{.Line}}">{{.Name}}</a></td><td class="percent">{{.Percent}}</td><td class="percent">{{.Covered}}/{{.Statements}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Lines}}<table class="source">
+{{range .Lines}}<tr id="L{{.Number}}" class="{{.Class}}"><td class="line">{{.Number}}</td><td>{{.Text}}</td></tr>
+{{end}}</table>
+{{else}}<p>Source code not available.</p>
+{{end}}</body>
+</html>
+`))
+)
+
+type htmlCounts struct {
+	Percent    string
+	Covered    int
+	Statements int
+}
+
+func newHTMLCounts(cc coverageCounts) htmlCounts {
+	return htmlCounts{percentFormat(cc.Percent()), cc.Covered, cc.Statements}
+}
+
+type htmlIndex struct {
+	htmlCounts
+	Style    template.CSS
+	Packages []*htmlPackage
+}
+
+type htmlPackage struct {
+	htmlCounts
+	Name  string
+	Files []*htmlFileLink
+}
+
+type htmlFileLink struct {
+	htmlCounts
+	Name string
+	Link string
+}
+
+type htmlFile struct {
+	htmlCounts
+	Name      string
+	Style     template.CSS
+	IndexLink string
+	Funcs     []*htmlFunc
+	Lines     []*htmlLine
+}
+
+type htmlFunc struct {
+	htmlCounts
+	Name string
+	Line int
+}
+
+type htmlLine struct {
+	Number int
+	Class  string
+	Text   string
+}
+
+// lineClass returns the CSS class for a line based on the blocks that contain it.
+func lineClass(blocks []*coverBlock, line int) string {
+	var covered, uncovered bool
+	for _, b := range blocks {
+		if b.NumStmt == 0 || line < b.StartLine || line > b.EndLine {
+			continue
+		}
+		if b.Count > 0 {
+			covered = true
+		} else {
+			uncovered = true
+		}
+	}
+	switch {
+	case covered && uncovered:
+		return "partial"
+	case covered:
+		return "covered"
+	case uncovered:
+		return "uncovered"
+	}
+	return ""
+}
+
+// writeHTMLReport writes an HTML coverage report (an index page plus one page
+// per file) to the provided directory.
+func writeHTMLReport(dir string, report *coverageReport, mod *goModule) error {
+	index := &htmlIndex{
+		htmlCounts: newHTMLCounts(report.Counts),
+		Style:      template.CSS(htmlStyle),
+	}
+
+	pkgs := maps.Keys(report.Packages)
+	slices.Sort(pkgs)
+	for _, pkg := range pkgs {
+		pc := report.Packages[pkg]
+		hp := &htmlPackage{
+			htmlCounts: newHTMLCounts(pc.Counts),
+			Name:       pkg,
+		}
+		for _, fc := range pc.Files {
+			name := mod.relativePath(fc.Name)
+			link := path.Join("files", strings.TrimPrefix(path.Clean("/"+name), "/")+".html")
+			hp.Files = append(hp.Files, &htmlFileLink{
+				htmlCounts: newHTMLCounts(fc.Counts),
+				Name:       name,
+				Link:       link,
+			})
+
+			hf, err := newHTMLFile(name, fc)
+			if err != nil {
+				return err
+			}
+			hf.IndexLink = strings.Repeat("../", strings.Count(link, "/")) + "index.html"
+			if err := writeTemplate(filepath.Join(dir, filepath.FromSlash(link)), htmlFileTemplate, hf); err != nil {
+				return err
+			}
+		}
+		index.Packages = append(index.Packages, hp)
+	}
+	return writeTemplate(filepath.Join(dir, "index.html"), htmlIndexTemplate, index)
+}
+
+func newHTMLFile(name string, fc *fileCoverage) (*htmlFile, error) {
+	hf := &htmlFile{
+		htmlCounts: newHTMLCounts(fc.Counts),
+		Name:       name,
+		Style:      template.CSS(htmlStyle),
+	}
+	for _, f := range fc.Funcs {
+		hf.Funcs = append(hf.Funcs, &htmlFunc{newHTMLCounts(f.Counts), f.Name, f.StartLine})
+	}
+
+	if fc.Path == "" {
+		return hf, nil
+	}
+	b, err := os.ReadFile(fc.Path)
+	if os.IsNotExist(err) {
+		return hf, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read source file: %v", err)
+	}
+	for i, text := range strings.Split(strings.TrimSuffix(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n"), "\n") {
+		hf.Lines = append(hf.Lines, &htmlLine{i + 1, lineClass(fc.Blocks, i+1), text})
+	}
+	return hf, nil
+}
+
+func writeTemplate(filename string, tmpl *template.Template, data any) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+	defer f.Close()
+	if err := tmpl.Execute(f, data); err != nil {
+		return fmt.Errorf("failed to execute template: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package gocli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLineClass(t *testing.T) {
+	blocks := []*coverBlock{
+		{StartLine: 1, EndLine: 2, NumStmt: 1, Count: 1},
+		{StartLine: 2, EndLine: 3, NumStmt: 1, Count: 0},
+		{StartLine: 5, EndLine: 5, NumStmt: 0, Count: 0},
+	}
+	for line, want := range map[int]string{
+		1: "covered",
+		2: "partial",
+		3: "uncovered",
+		4: "",
+		5: "",
+	} {
+		if got := lineClass(blocks, line); got != want {
+			t.Errorf("lineClass(%d) returned %q; want %q", line, got, want)
+		}
+	}
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	mod := testModule(t)
+	cp := &coverProfile{
+		Mode: "set",
+		Files: map[string][]*coverBlock{
+			testCoverFile: {
+				{StartLine: 3, StartCol: 26, EndLine: 4, EndCol: 7, NumStmt: 1, Count: 1},
+				{StartLine: 4, StartCol: 7, EndLine: 6, EndCol: 3, NumStmt: 1, Count: 0},
+				{StartLine: 7, StartCol: 2, EndLine: 7, EndCol: 10, NumStmt: 1, Count: 1},
+				{StartLine: 12, StartCol: 26, EndLine: 14, EndCol: 2, NumStmt: 1, Count: 0},
+			},
+			testModulePath + "/p1/missing.go": {
+				{StartLine: 1, StartCol: 1, EndLine: 1, EndCol: 10, NumStmt: 1, Count: 1},
+			},
+		},
+	}
+	dir := t.TempDir()
+	if err := writeHTMLReport(dir, computeCoverage(cp, mod), mod); err != nil {
+		t.Fatalf("writeHTMLReport() returned error: %v", err)
+	}
+
+	for _, test := range []struct {
+		file string
+		want []string
+	}{
+		{
+			file: "index.html",
+			want: []string{
+				"<p>Total coverage: 60.0% (3/5 statements)</p>",
+				"<h2>github.com/leep-frog/gocli/testdata/cover: 50.0%</h2>",
+				`<tr><td><a href="files/testdata/cover/cover.go.html">testdata/cover/cover.go</a></td><td class="percent">50.0%</td><td class="percent">2/4</td></tr>`,
+				`<tr><td><a href="files/p1/missing.go.html">p1/missing.go</a></td><td class="percent">100.0%</td><td class="percent">1/1</td></tr>`,
+			},
+		},
+		{
+			file: filepath.Join("files", "testdata", "cover", "cover.go.html"),
+			want: []string{
+				`<p><a href="../../../index.html">Index</a></p>`,
+				"<h1>testdata/cover/cover.go: 50.0%</h1>",
+				`<tr><td><a href="#L3">Covered</a></td><td class="percent">66.7%</td><td class="percent">2/3</td></tr>`,
+				`<tr><td><a href="#L12">(*thing).method</a></td><td class="percent">0.0%</td><td class="percent">0/1</td></tr>`,
+				`<tr id="L1" class=""><td class="line">1</td><td>package cover</td></tr>`,
+				`<tr id="L3" class="covered"><td class="line">3</td><td>func Covered(b bool) int {</td></tr>`,
+				`<tr id="L4" class="partial"><td class="line">4</td><td>	if b {</td></tr>`,
+				`<tr id="L13" class="uncovered"><td class="line">13</td><td>	println(&#34;hi&#34;)</td></tr>`,
+			},
+		},
+		{
+			file: filepath.Join("files", "p1", "missing.go.html"),
+			want: []string{
+				`<p><a href="../../index.html">Index</a></p>`,
+				"<p>Source code not available.</p>",
+			},
+		},
+	} {
+		t.Run(test.file, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join(dir, test.file))
+			if err != nil {
+				t.Fatalf("failed to read HTML file: %v", err)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(string(b), want) {
+					t.Errorf("%s doesn't contain %q:\n%s", test.file, want, b)
+				}
+			}
+		})
+	}
+}
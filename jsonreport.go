@@ -0,0 +1,154 @@
+package gocli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// jsonReportVersion is the version of the JSON report format. It must be
+// incremented whenever a backwards incompatible change is made to the format.
+const jsonReportVersion = 2
+
+var (
+	testResultNames = map[testResult]string{
+		noTestFiles:  "no-test-files",
+		testSuccess:  "pass",
+		testFailure:  "fail",
+		buildFailure: "build-failure",
+		setupFailure: "setup-failure",
+		testPanic:    "panic",
+		testTimeout:  "timeout",
+	}
+	testStatusNames = map[testStatus]string{
+		testRunning: "incomplete",
+		testPassed:  "pass",
+		testFailed:  "fail",
+		testSkipped: "skip",
+	}
+)
+
+func (tr testResult) String() string {
+	if s, ok := testResultNames[tr]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown(%d)", int(tr))
+}
+
+func (ts testStatus) String() string {
+	if s, ok := testStatusNames[ts]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown(%d)", int(ts))
+}
+
+// jsonReport is the document written by the `--json-out` flag.
+type jsonReport struct {
+	Version int `json:"version"`
+	// GoTestRuns are the arguments passed to `go` for each run (`--failed`
+	// runs `go test` once per package).
+	GoTestRuns [][]string `json:"goTestRuns"`
+	// CoverProfile is the path to the coverage profile (only set if the
+	// profile was kept with the `--coverprofile` flag).
+	CoverProfile string `json:"coverProfile,omitempty"`
+	// Coverage is the overall coverage of all packages (or nil if coverage
+	// wasn't computed).
+	Coverage *jsonCoverage `json:"coverage"`
+	// ExcludedStatements is the number of statements that were excluded from
+	// coverage.
+	ExcludedStatements int            `json:"excludedStatements,omitempty"`
+	Packages           []*jsonPackage `json:"packages"`
+}
+
+type jsonCoverage struct {
+	Percent    float64 `json:"percent"`
+	Statements int     `json:"statements"`
+	Covered    int     `json:"covered"`
+}
+
+type jsonPackage struct {
+	Name          string        `json:"name"`
+	Status        string        `json:"status"`
+	Elapsed       float64       `json:"elapsedSeconds"`
+	FailedTest    string        `json:"failedTest,omitempty"`
+	FailureDetail string        `json:"failureDetail,omitempty"`
+	Coverage      *jsonCoverage `json:"coverage"`
+	CoverageRule  string        `json:"coverageRule,omitempty"`
+	Tests         []*jsonTest   `json:"tests"`
+}
+
+type jsonTest struct {
+	Name     string      `json:"name"`
+	Status   string      `json:"status"`
+	Elapsed  float64     `json:"elapsedSeconds"`
+	Output   []string    `json:"output"`
+	Subtests []*jsonTest `json:"subtests,omitempty"`
+}
+
+func newJSONCoverage(cc coverageCounts) *jsonCoverage {
+	return &jsonCoverage{
+		Percent:    cc.Percent(),
+		Statements: cc.Statements,
+		Covered:    cc.Covered,
+	}
+}
+
+func newJSONTests(tests []*testCaseResult) []*jsonTest {
+	r := []*jsonTest{}
+	for _, tcr := range tests {
+		jt := &jsonTest{
+			Name:    tcr.Name,
+			Status:  tcr.Status.String(),
+			Elapsed: tcr.Elapsed,
+			Output:  tcr.Output,
+		}
+		if jt.Output == nil {
+			jt.Output = []string{}
+		}
+		if len(tcr.Subtests) > 0 {
+			jt.Subtests = newJSONTests(tcr.Subtests)
+		}
+		r = append(r, jt)
+	}
+	return r
+}
+
+// newJSONReport builds a JSON report from the results of the `go test` runs.
+func newJSONReport(runs [][]string, coverProfile string, packages []string, results map[string]*packageResult, tests map[string][]*testCaseResult, report *coverageReport) *jsonReport {
+	r := &jsonReport{
+		Version:      jsonReportVersion,
+		GoTestRuns:   runs,
+		CoverProfile: coverProfile,
+		Packages:     []*jsonPackage{},
+	}
+	if report != nil {
+		r.Coverage = newJSONCoverage(report.Counts)
+		r.ExcludedStatements = report.Excluded
+	}
+	for _, p := range packages {
+		pr := results[p]
+		jp := &jsonPackage{
+			Name:          p,
+			Status:        pr.TestResult.String(),
+			Elapsed:       pr.Elapsed,
+			FailedTest:    pr.FailedTest,
+			FailureDetail: pr.FailureDetail,
+			CoverageRule:  pr.CoverageRule,
+			Tests:         newJSONTests(tests[p]),
+		}
+		if pc := report.packageCoverage(p); pc != nil {
+			jp.Coverage = newJSONCoverage(pc.Counts)
+		}
+		r.Packages = append(r.Packages, jp)
+	}
+	return r
+}
+
+// writeJSONReport writes the JSON report to the provided file.
+func writeJSONReport(filename string, report *jsonReport) error {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %v", err)
+	}
+	return os.WriteFile(filename, append(b, '\n'), 0644)
+}
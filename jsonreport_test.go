@@ -0,0 +1,217 @@
+package gocli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/leep-frog/command/command"
+	"github.com/leep-frog/command/commandertest"
+	"github.com/leep-frog/command/commandtest"
+)
+
+func TestJSONReport(t *testing.T) {
+	results := map[string]*packageResult{
+		"p1": {TestResult: testSuccess, Elapsed: 1.5},
+		"p2": {TestResult: testPanic, FailedTest: "TestPanic", FailureDetail: "p2/p2_test.go:3"},
+	}
+	tests := map[string][]*testCaseResult{
+		"p1": {
+			{
+				Name:     "TestOne",
+				Status:   testPassed,
+				Elapsed:  1.25,
+				Output:   []string{"=== RUN   TestOne", "--- PASS: TestOne (1.25s)"},
+				Subtests: []*testCaseResult{{Name: "TestOne/sub", Status: testSkipped}},
+			},
+		},
+		"p2": {{Name: "TestPanic"}},
+	}
+	report := &coverageReport{
+		Counts: coverageCounts{Statements: 4, Covered: 1},
+		Packages: map[string]*packageCoverage{
+			"p1": {Name: "p1", Counts: coverageCounts{Statements: 4, Covered: 1}},
+		},
+	}
+
+	for _, test := range []struct {
+		name   string
+		report *coverageReport
+		want   *jsonReport
+	}{
+		{
+			name:   "builds report with coverage",
+			report: report,
+			want: &jsonReport{
+				Version:    jsonReportVersion,
+				GoTestRuns: [][]string{{"test", "-json"}},
+				Coverage:   &jsonCoverage{Percent: 25, Statements: 4, Covered: 1},
+				Packages: []*jsonPackage{
+					{
+						Name:     "p1",
+						Status:   "pass",
+						Elapsed:  1.5,
+						Coverage: &jsonCoverage{Percent: 25, Statements: 4, Covered: 1},
+						Tests: []*jsonTest{{
+							Name:     "TestOne",
+							Status:   "pass",
+							Elapsed:  1.25,
+							Output:   []string{"=== RUN   TestOne", "--- PASS: TestOne (1.25s)"},
+							Subtests: []*jsonTest{{Name: "TestOne/sub", Status: "skip", Output: []string{}}},
+						}},
+					},
+					{
+						Name:          "p2",
+						Status:        "panic",
+						FailedTest:    "TestPanic",
+						FailureDetail: "p2/p2_test.go:3",
+						Tests:         []*jsonTest{{Name: "TestPanic", Status: "incomplete", Output: []string{}}},
+					},
+				},
+			},
+		},
+		{
+			name: "builds report without coverage",
+			want: &jsonReport{
+				Version:    jsonReportVersion,
+				GoTestRuns: [][]string{{"test", "-json"}},
+				Packages: []*jsonPackage{
+					{
+						Name:    "p1",
+						Status:  "pass",
+						Elapsed: 1.5,
+						Tests: []*jsonTest{{
+							Name:     "TestOne",
+							Status:   "pass",
+							Elapsed:  1.25,
+							Output:   []string{"=== RUN   TestOne", "--- PASS: TestOne (1.25s)"},
+							Subtests: []*jsonTest{{Name: "TestOne/sub", Status: "skip", Output: []string{}}},
+						}},
+					},
+					{
+						Name:          "p2",
+						Status:        "panic",
+						FailedTest:    "TestPanic",
+						FailureDetail: "p2/p2_test.go:3",
+						Tests:         []*jsonTest{{Name: "TestPanic", Status: "incomplete", Output: []string{}}},
+					},
+				},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := newJSONReport([][]string{{"test", "-json"}}, "", []string{"p1", "p2"}, results, tests, test.report)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("newJSONReport() returned incorrect report (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestJSONOutFlag(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "report.json")
+	coverProfile := filepath.Join(dir, "cover.out")
+	commandtest.StubValue(t, &tmpFile, func() (*os.File, error) {
+		return os.Create(coverProfile)
+	})
+
+	commandertest.ExecuteTest(t, &commandtest.ExecuteTestCase{
+		Node:         CLI().Node(),
+		Args:         []string{"--json-out", filename},
+		RunResponses: []*commandtest.FakeRun{{Stdout: noTestEvents("p1")}},
+		WantStdout:   stdoutLines(noTestLine("p1")),
+		WantRunContents: []*commandtest.RunContents{{
+			Name: "go",
+			Args: []string{
+				"test",
+				"-json",
+				".",
+				fmt.Sprintf("-coverprofile=%s", coverProfile),
+			},
+		}},
+		WantData: &command.Data{Values: map[string]interface{}{
+			pathArgs.Name():        []string{"."},
+			minCoverageFlag.Name(): 0.0,
+			jsonOutFlag.Name():     filename,
+			"COVERAGE": map[string]*packageResult{
+				"p1": {
+					TestResult: noTestFiles,
+					Line:       noTestLine("p1"),
+				},
+			},
+		}},
+	})
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read JSON report: %v", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("failed to unmarshal JSON report: %v", err)
+	}
+	want := map[string]interface{}{
+		"version": float64(jsonReportVersion),
+		"goTestRuns": []interface{}{
+			[]interface{}{"test", "-json", ".", fmt.Sprintf("-coverprofile=%s", coverProfile)},
+		},
+		"coverage": map[string]interface{}{"percent": 0.0, "statements": 0.0, "covered": 0.0},
+		"packages": []interface{}{
+			map[string]interface{}{
+				"name":           "p1",
+				"status":         "no-test-files",
+				"elapsedSeconds": 0.0,
+				"coverage":       nil,
+				"tests":          []interface{}{},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("--json-out wrote incorrect report (-want, +got):\n%s", diff)
+	}
+}
+
+func TestReportsWrittenOnPackageCountMismatch(t *testing.T) {
+	dir := t.TempDir()
+	jsonOut := filepath.Join(dir, "report.json")
+	junitOut := filepath.Join(dir, "junit.xml")
+	coverProfile := filepath.Join(dir, "cover.out")
+	commandtest.StubValue(t, &tmpFile, func() (*os.File, error) {
+		return os.Create(coverProfile)
+	})
+
+	commandertest.ExecuteTest(t, &commandtest.ExecuteTestCase{
+		Node:         CLI().Node(),
+		Args:         []string{"--json-out", jsonOut, "-j", junitOut, "--package-count", "2"},
+		RunResponses: []*commandtest.FakeRun{{Stdout: noTestEvents("p1")}},
+		WantStdout:   stdoutLines(noTestLine("p1")),
+		WantStderr:   "Expected 2 packages, got 1:\np1\n",
+		WantErr:      fmt.Errorf("Expected 2 packages, got 1:\np1"),
+		WantRunContents: []*commandtest.RunContents{{
+			Name: "go",
+			Args: []string{
+				"test",
+				"-json",
+				".",
+				fmt.Sprintf("-coverprofile=%s", coverProfile),
+			},
+		}},
+		WantData: &command.Data{Values: map[string]interface{}{
+			pathArgs.Name():         []string{"."},
+			minCoverageFlag.Name():  0.0,
+			jsonOutFlag.Name():      jsonOut,
+			junitFlag.Name():        junitOut,
+			packageCountFlag.Name(): 2,
+		}},
+	})
+
+	for _, filename := range []string{jsonOut, junitOut} {
+		if _, err := os.Stat(filename); err != nil {
+			t.Errorf("report %s wasn't written: %v", filename, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package gocli
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// junitTestSuites is the root element of a JUnit XML report.
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite contains the results for a single package.
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Cases    []*junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase contains the result for a single test (or subtest).
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",chardata"`
+}
+
+func junitTime(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
+
+// junitReport builds a JUnit report from the results of a `go test` run.
+func junitReport(packages []string, results map[string]*packageResult, tests map[string][]*testCaseResult) *junitTestSuites {
+	r := &junitTestSuites{}
+	var elapsed float64
+	for _, p := range packages {
+		pr := results[p]
+		suite := &junitTestSuite{
+			Name: p,
+			Time: junitTime(pr.Elapsed),
+		}
+		elapsed += pr.Elapsed
+
+		for _, tcr := range allTests(tests[p]) {
+			tc := &junitTestCase{
+				ClassName: p,
+				Name:      tcr.Name,
+				Time:      junitTime(tcr.Elapsed),
+			}
+			output := strings.Join(dedent(ownOutput(tcr)), "\n")
+			switch {
+			case tcr.Name == pr.FailedTest && (pr.TestResult == testPanic || pr.TestResult == testTimeout):
+				tc.Error = &junitMessage{pr.failureMessage(p), output}
+				suite.Errors++
+			case tcr.Status == testFailed:
+				tc.Failure = &junitMessage{"Failed", output}
+				suite.Failures++
+			case tcr.Status == testSkipped:
+				tc.Skipped = &junitMessage{"Skipped", output}
+				suite.Skipped++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		// Packages that fail without running any tests (e.g. build failures) are
+		// reported as a single errored test case so the failure isn't lost.
+		if len(suite.Cases) == 0 && pr.TestResult != testSuccess && pr.TestResult != noTestFiles {
+			suite.Cases = append(suite.Cases, &junitTestCase{
+				ClassName: p,
+				Name:      "[package]",
+				Time:      junitTime(0),
+				Error:     &junitMessage{pr.failureMessage(p), pr.Line},
+			})
+			suite.Errors++
+		}
+
+		suite.Tests = len(suite.Cases)
+		r.Tests += suite.Tests
+		r.Failures += suite.Failures
+		r.Errors += suite.Errors
+		r.Skipped += suite.Skipped
+		r.Suites = append(r.Suites, suite)
+	}
+	r.Time = junitTime(elapsed)
+	return r
+}
+
+// allTests returns the provided tests and all of their subtests.
+func allTests(tests []*testCaseResult) []*testCaseResult {
+	var r []*testCaseResult
+	for _, tcr := range tests {
+		r = append(r, tcr)
+		r = append(r, allTests(tcr.Subtests)...)
+	}
+	return r
+}
+
+// writeJUnitReport writes the JUnit report to the provided file.
+func writeJUnitReport(filename string, report *junitTestSuites) error {
+	b, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %v", err)
+	}
+	contents := append([]byte(xml.Header), b...)
+	return os.WriteFile(filename, append(contents, '\n'), 0644)
+}
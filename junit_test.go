@@ -0,0 +1,138 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/leep-frog/command/command"
+	"github.com/leep-frog/command/commandertest"
+	"github.com/leep-frog/command/commandtest"
+)
+
+func TestJUnitReport(t *testing.T) {
+	results := map[string]*packageResult{
+		"p1": {TestResult: testFailure, Elapsed: 1.5},
+		"p2": {TestResult: buildFailure, FailureDetail: "p2/file.go:1:1: oops", Line: "FAIL\tp2 [build failed]"},
+		"p3": {TestResult: testPanic, FailedTest: "TestPanic", Elapsed: 0.25},
+		"p4": {TestResult: noTestFiles},
+	}
+	tests := map[string][]*testCaseResult{
+		"p1": {
+			{
+				Name:    "TestOne",
+				Status:  testFailed,
+				Elapsed: 1.25,
+				Output:  []string{"=== RUN   TestOne", "--- FAIL: TestOne (1.25s)"},
+				Subtests: []*testCaseResult{
+					{Name: "TestOne/passes", Status: testPassed, Elapsed: 0.5},
+					{Name: "TestOne/fails", Status: testFailed, Elapsed: 0.75, Output: []string{"    one_test.go:12: bad <value>", "    --- FAIL: TestOne/fails (0.75s)"}},
+				},
+			},
+			{Name: "TestSkip", Status: testSkipped, Output: []string{"    skip_test.go:3: not today"}},
+		},
+		"p3": {
+			{Name: "TestPanic", Output: []string{"panic: oops"}},
+		},
+	}
+
+	filename := filepath.Join(t.TempDir(), "junit.xml")
+	if err := writeJUnitReport(filename, junitReport([]string{"p1", "p2", "p3", "p4"}, results, tests)); err != nil {
+		t.Fatalf("writeJUnitReport() returned error: %v", err)
+	}
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read JUnit report: %v", err)
+	}
+
+	want := strings.Join([]string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<testsuites tests="6" failures="2" errors="2" skipped="1" time="1.750">`,
+		`  <testsuite name="p1" tests="4" failures="2" errors="0" skipped="1" time="1.500">`,
+		`    <testcase classname="p1" name="TestOne" time="1.250">`,
+		`      <failure message="Failed"></failure>`,
+		`    </testcase>`,
+		`    <testcase classname="p1" name="TestOne/passes" time="0.500"></testcase>`,
+		`    <testcase classname="p1" name="TestOne/fails" time="0.750">`,
+		`      <failure message="Failed">one_test.go:12: bad &lt;value&gt;</failure>`,
+		`    </testcase>`,
+		`    <testcase classname="p1" name="TestSkip" time="0.000">`,
+		`      <skipped message="Skipped">skip_test.go:3: not today</skipped>`,
+		`    </testcase>`,
+		`  </testsuite>`,
+		`  <testsuite name="p2" tests="1" failures="0" errors="1" skipped="0" time="0.000">`,
+		`    <testcase classname="p2" name="[package]" time="0.000">`,
+		`      <error message="Build failed for package: p2 (p2/file.go:1:1: oops)">FAIL&#x9;p2 [build failed]</error>`,
+		`    </testcase>`,
+		`  </testsuite>`,
+		`  <testsuite name="p3" tests="1" failures="0" errors="1" skipped="0" time="0.250">`,
+		`    <testcase classname="p3" name="TestPanic" time="0.000">`,
+		`      <error message="Test panicked in package p3: TestPanic">panic: oops</error>`,
+		`    </testcase>`,
+		`  </testsuite>`,
+		`  <testsuite name="p4" tests="0" failures="0" errors="0" skipped="0" time="0.000"></testsuite>`,
+		`</testsuites>`,
+		``,
+	}, "\n")
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("writeJUnitReport() wrote incorrect report (-want, +got):\n%s", diff)
+	}
+}
+
+func TestJUnitFlag(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "junit.xml")
+	coverProfile := filepath.Join(dir, "cover.out")
+	commandtest.StubValue(t, &tmpFile, func() (*os.File, error) {
+		return os.Create(coverProfile)
+	})
+
+	commandertest.ExecuteTest(t, &commandtest.ExecuteTestCase{
+		Node: CLI().Node(),
+		Args: []string{"--junit", filename},
+		RunResponses: []*commandtest.FakeRun{{
+			Stdout: concat(
+				[]string{
+					actionEvent("p1", "TestOne", "run"),
+					jsonEvent(&testEvent{Action: actionPass, Package: "p1", Test: "TestOne", Elapsed: 0.5}),
+				},
+				packageEvents("p1", actionPass, "ok  \tp1\t0.500s"),
+			),
+		}},
+		WantStdout: stdoutLines("ok  \tp1\t0.500s"),
+		WantRunContents: []*commandtest.RunContents{{
+			Name: "go",
+			Args: []string{
+				"test",
+				"-json",
+				".",
+				fmt.Sprintf("-coverprofile=%s", coverProfile),
+			},
+		}},
+		WantData: &command.Data{Values: map[string]interface{}{
+			pathArgs.Name():        []string{"."},
+			minCoverageFlag.Name(): 0.0,
+			junitFlag.Name():       filename,
+			"COVERAGE": map[string]*packageResult{
+				"p1": {
+					TestResult: testSuccess,
+					Line:       "ok  \tp1\t0.500s",
+				},
+			},
+			"TESTS": map[string][]*testCaseResult{
+				"p1": {{Name: "TestOne", Status: testPassed, Elapsed: 0.5}},
+			},
+		}},
+	})
+
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read JUnit report: %v", err)
+	}
+	if want := `<testcase classname="p1" name="TestOne" time="0.500"></testcase>`; !strings.Contains(string(got), want) {
+		t.Errorf("JUnit report doesn't contain %q:\n%s", want, got)
+	}
+}
@@ -0,0 +1,150 @@
+package gocli
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/leep-frog/command/command"
+	"github.com/leep-frog/command/commander"
+)
+
+var (
+	listJSONFlag = commander.BoolFlag("json", commander.FlagNoShortName, "If set, the tests are printed as JSON")
+)
+
+type jsonTestPackage struct {
+	ImportPath string          `json:"importPath,omitempty"`
+	Dir        string          `json:"dir"`
+	Funcs      []*jsonTestFunc `json:"funcs"`
+	Error      string          `json:"error,omitempty"`
+}
+
+type jsonTestFunc struct {
+	Kind     string   `json:"kind"`
+	Name     string   `json:"name"`
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Subtests []string `json:"subtests,omitempty"`
+}
+
+// listNode returns the node that lists the tests, benchmarks, fuzz targets,
+// and examples in the provided packages.
+func listNode() command.Node {
+	return commander.SerialNodes(
+		commander.Description("List the tests, benchmarks, fuzz targets, and examples in packages"),
+		commander.FlagProcessor(
+			listJSONFlag,
+		),
+		pathArgs,
+		&commander.ExecutorProcessor{F: func(o command.Output, d *command.Data) error {
+			pkgs, err := listTestPackages(pathArgs.GetOrDefault(d, []string{"."}))
+			if err != nil {
+				return o.Err(err)
+			}
+			wd, err := getwd()
+			if err != nil {
+				return o.Annotatef(err, "failed to get current directory")
+			}
+
+			if listJSONFlag.Get(d) {
+				b, err := json.MarshalIndent(newJSONTestPackages(pkgs, wd), "", "  ")
+				if err != nil {
+					return o.Annotatef(err, "failed to marshal tests")
+				}
+				o.Stdoutln(string(b))
+				return nil
+			}
+			printTestPackages(o, pkgs, wd)
+			// Packages that can't be loaded are reported, but don't prevent listing
+			// the other packages.
+			for _, tp := range pkgs {
+				if tp.Err != nil {
+					o.Stderrln(tp.Err)
+				}
+			}
+			return nil
+		}},
+	)
+}
+
+// listTestPackages returns the packages matched by paths (using the same
+// semantics as pathArgs) that contain at least one test function or that
+// couldn't be loaded.
+func listTestPackages(paths []string) ([]*testPackage, error) {
+	mod, err := currentGoModule()
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := findTestPackages(paths, mod)
+	if err != nil {
+		return nil, err
+	}
+	var r []*testPackage
+	for _, tp := range pkgs {
+		if len(tp.Funcs) > 0 || tp.Err != nil {
+			r = append(r, tp)
+		}
+	}
+	return r, nil
+}
+
+// displayPath returns the path relative to the working directory (if
+// possible) with forward slashes.
+func displayPath(wd, path string) string {
+	if filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+func newJSONTestPackages(pkgs []*testPackage, wd string) []*jsonTestPackage {
+	r := []*jsonTestPackage{}
+	for _, tp := range pkgs {
+		jp := &jsonTestPackage{
+			ImportPath: tp.ImportPath,
+			Dir:        displayPath(wd, tp.Dir),
+			Funcs:      []*jsonTestFunc{},
+		}
+		if tp.Err != nil {
+			jp.Error = tp.Err.Error()
+		}
+		for _, tf := range tp.Funcs {
+			jp.Funcs = append(jp.Funcs, &jsonTestFunc{
+				Kind:     tf.Kind.String(),
+				Name:     tf.Name,
+				File:     displayPath(wd, tf.File),
+				Line:     tf.Line,
+				Subtests: tf.Subtests,
+			})
+		}
+		r = append(r, jp)
+	}
+	return r
+}
+
+// printTestPackages prints the test functions of every package.
+func printTestPackages(o command.Output, pkgs []*testPackage, wd string) {
+	for _, tp := range pkgs {
+		if len(tp.Funcs) == 0 {
+			continue
+		}
+		name := tp.ImportPath
+		if name == "" {
+			name = displayPath(wd, tp.Dir)
+		}
+		o.Stdoutln(name)
+
+		var kindWidth, nameWidth int
+		for _, tf := range tp.Funcs {
+			kindWidth = max(kindWidth, len(tf.Kind.String()))
+			nameWidth = max(nameWidth, len(tf.Name))
+		}
+		for _, tf := range tp.Funcs {
+			o.Stdoutf("  %-*s  %-*s  %s\n", kindWidth, tf.Kind, nameWidth, tf.Name, fmt.Sprintf("%s:%d", displayPath(wd, tf.File), tf.Line))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package gocli
+
+import (
+	"testing"
+
+	"github.com/leep-frog/command/command"
+	"github.com/leep-frog/command/commandertest"
+	"github.com/leep-frog/command/commandtest"
+)
+
+func TestList(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		etc  *commandtest.ExecuteTestCase
+	}{
+		{
+			name: "lists tests",
+			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"list", "./testpkg"},
+				WantStdout: stdoutLines(
+					testModulePath+"/testpkg",
+					"  test  TestThis   testpkg/testpkg_test.go:6",
+					"  test  TestThat   testpkg/testpkg_test.go:9",
+					"  test  TestOther  testpkg/testpkg_test.go:12",
+				),
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name(): []string{"./testpkg"},
+				}},
+			},
+		},
+		{
+			name: "lists tests as JSON",
+			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"list", testModulePath + "/testpkg", "--json"},
+				WantStdout: stdoutLines(
+					`[`,
+					`  {`,
+					`    "importPath": "`+testModulePath+`/testpkg",`,
+					`    "dir": "testpkg",`,
+					`    "funcs": [`,
+					`      {`,
+					`        "kind": "test",`,
+					`        "name": "TestThis",`,
+					`        "file": "testpkg/testpkg_test.go",`,
+					`        "line": 6`,
+					`      },`,
+					`      {`,
+					`        "kind": "test",`,
+					`        "name": "TestThat",`,
+					`        "file": "testpkg/testpkg_test.go",`,
+					`        "line": 9`,
+					`      },`,
+					`      {`,
+					`        "kind": "test",`,
+					`        "name": "TestOther",`,
+					`        "file": "testpkg/testpkg_test.go",`,
+					`        "line": 12`,
+					`      }`,
+					`    ]`,
+					`  }`,
+					`]`,
+				),
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name():     []string{testModulePath + "/testpkg"},
+					listJSONFlag.Name(): true,
+				}},
+			},
+		},
+		{
+			name: "lists nothing when there are no tests",
+			etc: &commandtest.ExecuteTestCase{
+				Args:       []string{"list", "./testdata/cover"},
+				WantStdout: "",
+				WantData: &command.Data{Values: map[string]interface{}{
+					pathArgs.Name(): []string{"./testdata/cover"},
+				}},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			test.etc.Node = CLI().Node()
+			commandertest.ExecuteTest(t, test.etc)
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package gocli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/leep-frog/command/command"
+	"github.com/leep-frog/command/commander"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+var (
+	mergeProfilesArg = commander.ListArg[string]("PROFILE", "Coverage profiles to merge", 1, command.UnboundedList, &commander.FileCompleter[[]string]{Distinct: true})
+	mergeOutputFlag  = commander.Flag[string]("output", 'o', "If set, the merged coverage profile is written to this file", &commander.FileCompleter[string]{})
+)
+
+// mergeNode returns the node that merges coverage profiles from separate
+// `go test` runs and checks the combined coverage.
+func mergeNode() command.Node {
+	return commander.SerialNodes(
+		commander.Description("Merge coverage profiles from multiple test runs and check the combined coverage"),
+		commander.FlagProcessor(
+			minCoverageFlag,
+			coverageRuleFlag,
+			excludeFlag,
+			diffBaseFlag,
+			uncoveredFlag,
+			snippetsFlag,
+			funcsFlag,
+			requireExpFlag,
+			noRequireExpFlag,
+			mergeOutputFlag,
+			htmlFlag,
+			badgeFlag,
+			lcovFlag,
+			coberturaFlag,
+		),
+		mergeProfilesArg,
+		commander.SuperSimpleProcessor(applyMergeConfig),
+		&commander.ExecutorProcessor{F: func(o command.Output, d *command.Data) error {
+			mod, err := currentGoModule()
+			if err != nil {
+				return o.Annotatef(err, "failed to find go module")
+			}
+			wd, err := getwd()
+			if err != nil {
+				return o.Annotatef(err, "failed to get current directory")
+			}
+			policy, err := newCoveragePolicy(o, d, mod, wd)
+			if err != nil {
+				return err
+			}
+
+			var profiles []*coverProfile
+			for _, filename := range mergeProfilesArg.Get(d) {
+				cp, err := readCoverProfile(filename)
+				if err != nil {
+					return o.Annotatef(err, "failed to read coverage profile %s", filename)
+				}
+				profiles = append(profiles, cp)
+			}
+			cp, err := mergeCoverProfiles(profiles)
+			if err != nil {
+				return o.Annotatef(err, "failed to merge coverage profiles")
+			}
+			if mergeOutputFlag.Provided(d) {
+				if err := writeCoverProfile(mergeOutputFlag.Get(d), cp); err != nil {
+					return o.Annotatef(err, "failed to write merged coverage profile")
+				}
+			}
+
+			var dc *diffCoverage
+			report := policy.report(cp, mod)
+			if diffBaseFlag.Provided(d) {
+				gitRoot, changes, err := gitChanges(o, d, diffBaseFlag.Get(d))
+				if err != nil {
+					return o.Err(err)
+				}
+				dc = computeDiffCoverage(report, gitRoot, changes)
+			}
+
+			packages := maps.Keys(report.Packages)
+			slices.Sort(packages)
+			results := map[string]*packageResult{}
+			for _, p := range packages {
+				pc := report.Packages[p]
+				results[p] = &packageResult{
+					TestResult: testSuccess,
+					Coverage:   pc.Counts.Percent(),
+					Line:       fmt.Sprintf("%s\tcoverage: %s of statements", p, percentFormat(pc.Counts.Percent())),
+				}
+			}
+			markNoTestFiles(results, mod)
+			for _, p := range packages {
+				if results[p].TestResult == noTestFiles {
+					results[p].Line += " [no test files]"
+				}
+				o.Stdoutln(results[p].Line)
+			}
+			o.Stdoutf("total\tcoverage: %s of statements\n", percentFormat(report.Counts.Percent()))
+			if report.Excluded > 0 {
+				o.Stdoutf("Excluded %d statements from coverage\n", report.Excluded)
+			}
+
+			retErr := policy.check(o, packages, results, report, dc, mod)
+
+			if uncoveredFlag.Get(d) {
+				printUncovered(o, uncoveredFiles(report, mod), snippetsFlag.Get(d))
+			}
+			if funcsFlag.Get(d) {
+				printFuncCoverage(o, funcListings(report, mod))
+			}
+
+			if err := writeCoverageReports(o, d, report, mod); err != nil {
+				return err
+			}
+
+			// Set data for use in tests
+			if len(results) > 0 {
+				d.Set("COVERAGE", results)
+			}
+			return retErr
+		}},
+	)
+}
+
+// mergedCoverMode returns the cover mode to use when merging profiles with
+// the provided modes. Set mode only records whether or not a block was run,
+// so it takes precedence over the count modes (count and atomic).
+func mergedCoverMode(a, b string) string {
+	switch {
+	case a == "" || a == b:
+		return b
+	case b == "":
+		return a
+	case a == "set" || b == "set":
+		return "set"
+	}
+	return "count"
+}
+
+// mergeCoverProfiles merges the provided coverage profiles into a single
+// profile. The counts of blocks that appear in multiple profiles are summed
+// (or OR-ed if the merged profile uses set mode).
+func mergeCoverProfiles(profiles []*coverProfile) (*coverProfile, error) {
+	merged := &coverProfile{
+		Files: map[string][]*coverBlock{},
+	}
+	for _, cp := range profiles {
+		merged.Mode = mergedCoverMode(merged.Mode, cp.Mode)
+	}
+	if merged.Mode == "" {
+		merged.Mode = "set"
+	}
+
+	blocks := map[string]map[[4]int]*coverBlock{}
+	for _, cp := range profiles {
+		for name, fileBlocks := range cp.Files {
+			mergedBlocks, ok := blocks[name]
+			if !ok {
+				mergedBlocks = map[[4]int]*coverBlock{}
+				blocks[name] = mergedBlocks
+			}
+			for _, cb := range fileBlocks {
+				count := cb.Count
+				if merged.Mode == "set" && count > 0 {
+					count = 1
+				}
+				if prev, ok := mergedBlocks[cb.key()]; ok {
+					if prev.NumStmt != cb.NumStmt {
+						return nil, fmt.Errorf("inconsistent statement count for block %d.%d,%d.%d in %s (profiles must be generated from the same source code)", cb.StartLine, cb.StartCol, cb.EndLine, cb.EndCol, name)
+					}
+					prev.Count = mergeCounts(merged.Mode, prev.Count, count)
+					continue
+				}
+				mb := *cb
+				mb.Count = count
+				mergedBlocks[cb.key()] = &mb
+				merged.Files[name] = append(merged.Files[name], &mb)
+			}
+		}
+	}
+
+	for _, fileBlocks := range merged.Files {
+		sortBlocks(fileBlocks)
+	}
+	return merged, nil
+}
+
+// writeCoverProfile writes the coverage profile to the provided file in the
+// same format used by `go test -coverprofile`.
+func writeCoverProfile(filename string, cp *coverProfile) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "mode: %s\n", cp.Mode)
+	names := maps.Keys(cp.Files)
+	slices.Sort(names)
+	for _, name := range names {
+		for _, cb := range cp.Files[name] {
+			fmt.Fprintf(&buf, "%s:%d.%d,%d.%d %d %d\n", name, cb.StartLine, cb.StartCol, cb.EndLine, cb.EndCol, cb.NumStmt, cb.Count)
+		}
+	}
+	return os.WriteFile(filename, buf.Bytes(), 0644)
+}
@@ -0,0 +1,292 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/leep-frog/command/command"
+	"github.com/leep-frog/command/commandertest"
+	"github.com/leep-frog/command/commandtest"
+)
+
+func TestMergeCoverProfiles(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		profiles []string
+		want     *coverProfile
+		wantErr  error
+	}{
+		{
+			name: "sums counts",
+			profiles: []string{
+				"mode: count\np/a.go:1.1,2.2 1 2\np/a.go:3.1,4.2 2 0\n",
+				"mode: count\np/a.go:3.1,4.2 2 3\np/a.go:1.1,2.2 1 1\np/b.go:1.1,1.9 1 0\n",
+			},
+			want: &coverProfile{
+				Mode: "count",
+				Files: map[string][]*coverBlock{
+					"p/a.go": {
+						{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 2, NumStmt: 1, Count: 3},
+						{StartLine: 3, StartCol: 1, EndLine: 4, EndCol: 2, NumStmt: 2, Count: 3},
+					},
+					"p/b.go": {
+						{StartLine: 1, StartCol: 1, EndLine: 1, EndCol: 9, NumStmt: 1, Count: 0},
+					},
+				},
+			},
+		},
+		{
+			name: "ORs counts in set mode",
+			profiles: []string{
+				"mode: set\np/a.go:1.1,2.2 1 1\np/a.go:3.1,4.2 2 0\n",
+				"mode: set\np/a.go:1.1,2.2 1 1\np/a.go:3.1,4.2 2 0\n",
+			},
+			want: &coverProfile{
+				Mode: "set",
+				Files: map[string][]*coverBlock{
+					"p/a.go": {
+						{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 2, NumStmt: 1, Count: 1},
+						{StartLine: 3, StartCol: 1, EndLine: 4, EndCol: 2, NumStmt: 2, Count: 0},
+					},
+				},
+			},
+		},
+		{
+			name: "uses set mode if any profile uses set mode",
+			profiles: []string{
+				"mode: atomic\np/a.go:1.1,2.2 1 5\np/a.go:3.1,4.2 2 0\n",
+				"mode: set\np/a.go:3.1,4.2 2 1\n",
+			},
+			want: &coverProfile{
+				Mode: "set",
+				Files: map[string][]*coverBlock{
+					"p/a.go": {
+						{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 2, NumStmt: 1, Count: 1},
+						{StartLine: 3, StartCol: 1, EndLine: 4, EndCol: 2, NumStmt: 2, Count: 1},
+					},
+				},
+			},
+		},
+		{
+			name: "uses count mode for count and atomic profiles",
+			profiles: []string{
+				"mode: atomic\np/a.go:1.1,2.2 1 5\n",
+				"mode: count\np/a.go:1.1,2.2 1 2\n",
+			},
+			want: &coverProfile{
+				Mode: "count",
+				Files: map[string][]*coverBlock{
+					"p/a.go": {
+						{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 2, NumStmt: 1, Count: 7},
+					},
+				},
+			},
+		},
+		{
+			name: "ignores empty profiles",
+			profiles: []string{
+				"",
+				"mode: count\np/a.go:1.1,2.2 1 2\n",
+			},
+			want: &coverProfile{
+				Mode: "count",
+				Files: map[string][]*coverBlock{
+					"p/a.go": {
+						{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 2, NumStmt: 1, Count: 2},
+					},
+				},
+			},
+		},
+		{
+			name: "fails if statement counts differ",
+			profiles: []string{
+				"mode: set\np/a.go:1.1,2.2 1 1\n",
+				"mode: set\np/a.go:1.1,2.2 2 1\n",
+			},
+			wantErr: fmt.Errorf("inconsistent statement count for block 1.1,2.2 in p/a.go (profiles must be generated from the same source code)"),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var profiles []*coverProfile
+			for _, s := range test.profiles {
+				cp, err := parseCoverProfile(strings.NewReader(s))
+				if err != nil {
+					t.Fatalf("parseCoverProfile(%q) returned error: %v", s, err)
+				}
+				profiles = append(profiles, cp)
+			}
+
+			got, err := mergeCoverProfiles(profiles)
+			if diff := cmp.Diff(test.wantErr, err, cmpErrors()); diff != "" {
+				t.Errorf("mergeCoverProfiles() returned incorrect error (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mergeCoverProfiles() returned incorrect profile (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	dir := t.TempDir()
+	unit := filepath.Join(dir, "unit.out")
+	integration := filepath.Join(dir, "integration.out")
+	untested := filepath.Join(dir, "untested.out")
+	merged := filepath.Join(dir, "merged.out")
+	for filename, lines := range map[string][]string{
+		unit:        profileLines("set", profileBlocks("p1", 2, 4), profileBlocks("p2", 1, 4)),
+		integration: profileLines("count", profileBlocks("p1", 3, 4)),
+		untested:    profileLines("set", profileBlocks(testModulePath+"/testdata/cover", 1, 4)),
+	} {
+		if err := os.WriteFile(filename, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+			t.Fatalf("failed to write coverage profile: %v", err)
+		}
+	}
+
+	for _, test := range []struct {
+		name string
+		etc  *commandtest.ExecuteTestCase
+		want []string
+	}{
+		{
+			name: "merges profiles",
+			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"merge", unit, integration, "-o", merged},
+				WantStdout: stdoutLines(
+					"p1\tcoverage: 75.0% of statements",
+					"p2\tcoverage: 25.0% of statements",
+					"total\tcoverage: 50.0% of statements",
+				),
+				WantData: &command.Data{Values: map[string]interface{}{
+					mergeProfilesArg.Name(): []string{unit, integration},
+					mergeOutputFlag.Name():  merged,
+					minCoverageFlag.Name():  0.0,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: testSuccess,
+							Coverage:   75,
+							Line:       "p1\tcoverage: 75.0% of statements",
+						},
+						"p2": {
+							TestResult: testSuccess,
+							Coverage:   25,
+							Line:       "p2\tcoverage: 25.0% of statements",
+						},
+					},
+				}},
+			},
+			want: profileLines("set", profileBlocks("p1", 3, 4), profileBlocks("p2", 1, 4)),
+		},
+		{
+			name: "checks coverage thresholds",
+			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"merge", unit, integration, "-m", "50", "-c", "p2=20"},
+				WantStdout: stdoutLines(
+					"p1\tcoverage: 75.0% of statements",
+					"p2\tcoverage: 25.0% of statements",
+					"total\tcoverage: 50.0% of statements",
+				),
+				WantData: &command.Data{Values: map[string]interface{}{
+					mergeProfilesArg.Name(): []string{unit, integration},
+					minCoverageFlag.Name():  50.0,
+					coverageRuleFlag.Name(): []string{"p2=20"},
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: testSuccess,
+							Coverage:   75,
+							Line:       "p1\tcoverage: 75.0% of statements",
+						},
+						"p2": {
+							TestResult:   testSuccess,
+							Coverage:     25,
+							CoverageRule: "p2=20",
+							Line:         "p2\tcoverage: 25.0% of statements",
+						},
+					},
+				}},
+			},
+		},
+		{
+			name: "fails if coverage is too low",
+			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"merge", unit, "-m", "50"},
+				WantStdout: stdoutLines(
+					"p1\tcoverage: 50.0% of statements",
+					"p2\tcoverage: 25.0% of statements",
+					"total\tcoverage: 37.5% of statements",
+				),
+				WantErr:    fmt.Errorf(`Coverage of package "p2" (25.0%%) must be at least 50.0%%`),
+				WantStderr: "Coverage of package \"p2\" (25.0%) must be at least 50.0%\n",
+				WantData: &command.Data{Values: map[string]interface{}{
+					mergeProfilesArg.Name(): []string{unit},
+					minCoverageFlag.Name():  50.0,
+					"COVERAGE": map[string]*packageResult{
+						"p1": {
+							TestResult: testSuccess,
+							Coverage:   50,
+							Line:       "p1\tcoverage: 50.0% of statements",
+						},
+						"p2": {
+							TestResult: testSuccess,
+							Coverage:   25,
+							Line:       "p2\tcoverage: 25.0% of statements",
+						},
+					},
+				}},
+			},
+		},
+		{
+			name: "doesn't check packages without test files",
+			etc: &commandtest.ExecuteTestCase{
+				Args: []string{"merge", untested, "-m", "50"},
+				WantStdout: stdoutLines(
+					testModulePath+"/testdata/cover\tcoverage: 25.0% of statements [no test files]",
+					"total\tcoverage: 25.0% of statements",
+				),
+				WantData: &command.Data{Values: map[string]interface{}{
+					mergeProfilesArg.Name(): []string{untested},
+					minCoverageFlag.Name():  50.0,
+					"COVERAGE": map[string]*packageResult{
+						testModulePath + "/testdata/cover": {
+							TestResult: noTestFiles,
+							Coverage:   25,
+							Line:       testModulePath + "/testdata/cover\tcoverage: 25.0% of statements [no test files]",
+						},
+					},
+				}},
+			},
+		},
+		{
+			name: "fails if profile doesn't exist",
+			etc: &commandtest.ExecuteTestCase{
+				Args:       []string{"merge", filepath.Join(dir, "missing.out")},
+				WantErr:    fmt.Errorf("failed to read coverage profile %s: failed to open coverage profile: open %s: no such file or directory", filepath.Join(dir, "missing.out"), filepath.Join(dir, "missing.out")),
+				WantStderr: fmt.Sprintf("failed to read coverage profile %s: failed to open coverage profile: open %s: no such file or directory\n", filepath.Join(dir, "missing.out"), filepath.Join(dir, "missing.out")),
+				WantData: &command.Data{Values: map[string]interface{}{
+					mergeProfilesArg.Name(): []string{filepath.Join(dir, "missing.out")},
+					minCoverageFlag.Name():  0.0,
+				}},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			test.etc.Node = CLI().Node()
+			commandertest.ExecuteTest(t, test.etc)
+
+			if test.want == nil {
+				return
+			}
+			b, err := os.ReadFile(merged)
+			if err != nil {
+				t.Fatalf("failed to read merged coverage profile: %v", err)
+			}
+			if diff := cmp.Diff(strings.Join(test.want, "\n")+"\n", string(b)); diff != "" {
+				t.Errorf("merge wrote incorrect coverage profile (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package gocli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// goModule contains information about the go module being tested.
+type goModule struct {
+	// Path is the module path (as declared in the go.mod file).
+	Path string
+	// Dir is the directory that contains the go.mod file.
+	Dir string
+}
+
+// findGoModule walks up from the provided directory until it finds a go.mod
+// file. A nil module (and nil error) is returned if no go.mod file is found.
+func findGoModule(dir string) (*goModule, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %v", err)
+	}
+
+	for {
+		path, err := readModulePath(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			return nil, err
+		}
+		if path != "" {
+			return &goModule{path, dir}, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+var (
+	// getwd is a variable so it can be stubbed in tests.
+	getwd = os.Getwd
+)
+
+// currentGoModule returns the go module for the current directory.
+func currentGoModule() (*goModule, error) {
+	wd, err := getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current directory: %v", err)
+	}
+	return findGoModule(wd)
+}
+
+// readModulePath returns the module path declared in the provided go.mod file
+// (or an empty string if the file does not exist).
+func readModulePath(goMod string) (string, error) {
+	f, err := os.Open(goMod)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to open go.mod file: %v", err)
+	}
+	defer f.Close()
+
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "module" {
+			// The path may be quoted (e.g. `module "example.com/m"`).
+			if path, err := strconv.Unquote(fields[1]); err == nil {
+				return path, nil
+			}
+			return fields[1], nil
+		}
+	}
+	return "", fmt.Errorf("no module directive in %s", goMod)
+}
+
+// sourcePath returns the local file path for a file name from a coverage
+// profile (or an empty string if the file is not in this module).
+func (m *goModule) sourcePath(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	if m == nil {
+		return ""
+	}
+	if rel, ok := strings.CutPrefix(name, m.Path+"/"); ok {
+		return filepath.Join(m.Dir, filepath.FromSlash(rel))
+	}
+	return ""
+}
+
+// relativePath returns the path of a file from a coverage profile relative to
+// the module root (or the name itself if the file is not in this module).
+func (m *goModule) relativePath(name string) string {
+	if m == nil {
+		return name
+	}
+	if filepath.IsAbs(name) {
+		if rel, err := filepath.Rel(m.Dir, name); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+		return name
+	}
+	if rel, ok := strings.CutPrefix(name, m.Path+"/"); ok {
+		return rel
+	}
+	return name
+}
+
+// importPath returns the import path of the package in the provided directory
+// (or an empty string if the directory isn't in the module).
+func (m *goModule) importPath(dir string) string {
+	if m == nil {
+		return ""
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(m.Dir, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	if rel == "." {
+		return m.Path
+	}
+	return m.Path + "/" + filepath.ToSlash(rel)
+}
@@ -0,0 +1,218 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFindGoModule(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	noModule := t.TempDir()
+	badModule := t.TempDir()
+	if err := os.WriteFile(filepath.Join(badModule, "go.mod"), []byte("go 1.21\n"), 0644); err != nil {
+		t.Fatalf("failed to write go.mod file: %v", err)
+	}
+
+	for _, test := range []struct {
+		name    string
+		dir     string
+		want    *goModule
+		wantErr error
+	}{
+		{
+			name: "finds module in directory",
+			dir:  wd,
+			want: &goModule{testModulePath, wd},
+		},
+		{
+			name: "finds module in parent directory",
+			dir:  filepath.Join(wd, "testdata", "cover"),
+			want: &goModule{testModulePath, wd},
+		},
+		{
+			name: "returns nil if no module",
+			dir:  noModule,
+		},
+		{
+			name:    "fails if no module directive",
+			dir:     badModule,
+			wantErr: fmt.Errorf("no module directive in %s", filepath.Join(badModule, "go.mod")),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := findGoModule(test.dir)
+			if diff := cmp.Diff(test.wantErr, err, cmpErrors()); diff != "" {
+				t.Errorf("findGoModule(%q) returned incorrect error (-want, +got):\n%s", test.dir, diff)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("findGoModule(%q) returned incorrect module (-want, +got):\n%s", test.dir, diff)
+			}
+		})
+	}
+}
+
+func TestSourcePath(t *testing.T) {
+	mod := &goModule{"example.com/mod", filepath.FromSlash("/src/mod")}
+	abs, err := filepath.Abs(filepath.FromSlash("/abs/file.go"))
+	if err != nil {
+		t.Fatalf("failed to get absolute path: %v", err)
+	}
+
+	for _, test := range []struct {
+		name string
+		mod  *goModule
+		file string
+		want string
+	}{
+		{
+			name: "resolves module file",
+			mod:  mod,
+			file: "example.com/mod/pkg/file.go",
+			want: filepath.Join(mod.Dir, "pkg", "file.go"),
+		},
+		{
+			name: "ignores file outside of module",
+			mod:  mod,
+			file: "example.com/other/pkg/file.go",
+		},
+		{
+			name: "ignores module path prefix",
+			mod:  mod,
+			file: "example.com/module/file.go",
+		},
+		{
+			name: "handles nil module",
+			file: "example.com/mod/pkg/file.go",
+		},
+		{
+			name: "returns absolute path",
+			file: abs,
+			want: abs,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.mod.sourcePath(test.file); got != test.want {
+				t.Errorf("sourcePath(%q) returned %q; want %q", test.file, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	abs, err := filepath.Abs(filepath.FromSlash("/src/mod/pkg/file.go"))
+	if err != nil {
+		t.Fatalf("failed to get absolute path: %v", err)
+	}
+	mod := &goModule{"example.com/mod", filepath.Dir(filepath.Dir(abs))}
+
+	for _, test := range []struct {
+		name string
+		mod  *goModule
+		file string
+		want string
+	}{
+		{
+			name: "returns path relative to module",
+			mod:  mod,
+			file: "example.com/mod/pkg/file.go",
+			want: "pkg/file.go",
+		},
+		{
+			name: "returns absolute path relative to module",
+			mod:  mod,
+			file: abs,
+			want: "pkg/file.go",
+		},
+		{
+			name: "returns name of file outside of module",
+			mod:  mod,
+			file: "example.com/other/file.go",
+			want: "example.com/other/file.go",
+		},
+		{
+			name: "returns name if nil module",
+			file: "example.com/mod/pkg/file.go",
+			want: "example.com/mod/pkg/file.go",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.mod.relativePath(test.file); got != test.want {
+				t.Errorf("relativePath(%q) returned %q; want %q", test.file, got, test.want)
+			}
+		})
+	}
+}
+
+func TestImportPath(t *testing.T) {
+	dir, err := filepath.Abs(filepath.FromSlash("/src/mod"))
+	if err != nil {
+		t.Fatalf("failed to get absolute path: %v", err)
+	}
+	mod := &goModule{"example.com/mod", dir}
+
+	for _, test := range []struct {
+		mod  *goModule
+		dir  string
+		want string
+	}{
+		{mod: mod, dir: dir, want: "example.com/mod"},
+		{mod: mod, dir: filepath.Join(dir, "pkg", "sub"), want: "example.com/mod/pkg/sub"},
+		{mod: mod, dir: filepath.Join(dir, "..", "other")},
+		{mod: mod, dir: filepath.Join(dir, "..", "mod2")},
+		{dir: dir},
+	} {
+		if got := test.mod.importPath(test.dir); got != test.want {
+			t.Errorf("importPath(%q) returned %q; want %q", test.dir, got, test.want)
+		}
+	}
+}
+
+func TestReadModulePath(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		contents string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "reads module path",
+			contents: "module example.com/m\n\ngo 1.21\n",
+			want:     "example.com/m",
+		},
+		{
+			name:     "strips comments",
+			contents: "// The module.\nmodule example.com/m // deprecated\n",
+			want:     "example.com/m",
+		},
+		{
+			name:     "unquotes path",
+			contents: "module \"example.com/m\"\n",
+			want:     "example.com/m",
+		},
+		{
+			name:     "requires whitespace after keyword",
+			contents: "modulefoo\n",
+			wantErr:  true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			goMod := filepath.Join(t.TempDir(), "go.mod")
+			writeFile(t, goMod, test.contents)
+			got, err := readModulePath(goMod)
+			if (err != nil) != test.wantErr {
+				t.Errorf("readModulePath() returned error %v; want error: %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("readModulePath() returned %q; want %q", got, test.want)
+			}
+		})
+	}
+}
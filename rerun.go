@@ -0,0 +1,119 @@
+package gocli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// lastFailures are the failures from previous runs, which are rerun by the
+// `--failed` flag.
+type lastFailures struct {
+	// Packages is a map from package import path to the names of the
+	// top-level tests that failed. An empty list indicates that the package
+	// failed without any failing tests (e.g. a build failure), in which case
+	// all of the package's tests are rerun.
+	Packages map[string][]string `json:"packages"`
+}
+
+// failuresFile returns the path of the file that stores the failures for the
+// provided module (or directory, if not in a module).
+func failuresFile(mod *goModule, wd string) (string, error) {
+	cacheDir, err := userCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user cache directory: %v", err)
+	}
+	key := wd
+	if mod != nil {
+		key = mod.Path
+	}
+	return filepath.Join(cacheDir, "gocli", "failures", url.PathEscape(key)+".json"), nil
+}
+
+// loadFailures reads the failures file (or returns no failures if the file
+// doesn't exist).
+func loadFailures(mod *goModule, wd string) (*lastFailures, error) {
+	filename, err := failuresFile(mod, wd)
+	if err != nil {
+		return nil, err
+	}
+
+	lf := &lastFailures{Packages: map[string][]string{}}
+	b, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return lf, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read failures file: %v", err)
+	}
+	if err := json.Unmarshal(b, lf); err != nil {
+		return nil, fmt.Errorf("failed to parse failures file %s: %v", filename, err)
+	}
+	if lf.Packages == nil {
+		lf.Packages = map[string][]string{}
+	}
+	return lf, nil
+}
+
+// saveFailures updates the failures file with the results of a run.
+func saveFailures(mod *goModule, wd string, results map[string]*packageResult, tests map[string][]*testCaseResult) error {
+	lf, err := loadFailures(mod, wd)
+	if err != nil {
+		return err
+	}
+	lf.update(results, tests)
+
+	filename, err := failuresFile(mod, wd)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(lf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal failures: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to create failures directory: %v", err)
+	}
+	return os.WriteFile(filename, append(b, '\n'), 0644)
+}
+
+// update replaces the failures of every package that was just tested.
+func (lf *lastFailures) update(results map[string]*packageResult, tests map[string][]*testCaseResult) {
+	for p, pr := range results {
+		delete(lf.Packages, p)
+		if pr.TestResult == testSuccess || pr.TestResult == noTestFiles {
+			continue
+		}
+		names := []string{}
+		for _, tcr := range tests[p] {
+			// Tests that are still running are the ones that panicked or timed out.
+			if tcr.Status == testFailed || tcr.Status == testRunning {
+				names = append(names, tcr.Name)
+			}
+		}
+		lf.Packages[p] = names
+	}
+}
+
+// sortedPackages returns the packages with failures in alphabetical order.
+func (lf *lastFailures) sortedPackages() []string {
+	packages := maps.Keys(lf.Packages)
+	slices.Sort(packages)
+	return packages
+}
+
+// exactTestsPattern returns a `-run` pattern that only matches the provided
+// top-level tests.
+func exactTestsPattern(tests []string) string {
+	var quoted []string
+	for _, test := range tests {
+		quoted = append(quoted, regexp.QuoteMeta(test))
+	}
+	return fmt.Sprintf("^(%s)$", strings.Join(quoted, "|"))
+}
@@ -0,0 +1,148 @@
+package gocli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/leep-frog/command/command"
+	"github.com/leep-frog/command/commandertest"
+	"github.com/leep-frog/command/commandtest"
+)
+
+func TestLastFailuresUpdate(t *testing.T) {
+	lf := &lastFailures{Packages: map[string][]string{
+		"p1": {"TestOld"},
+		"p2": {"TestOld"},
+		"p5": {"TestUntouched"},
+	}}
+	lf.update(map[string]*packageResult{
+		"p1": {TestResult: testSuccess},
+		"p2": {TestResult: testFailure},
+		"p3": {TestResult: buildFailure},
+		"p4": {TestResult: testPanic},
+	}, map[string][]*testCaseResult{
+		"p2": {
+			{Name: "TestPass", Status: testPassed},
+			{Name: "TestFail", Status: testFailed, Subtests: []*testCaseResult{{Name: "TestFail/case", Status: testFailed}}},
+			{Name: "TestSkip", Status: testSkipped},
+		},
+		"p4": {
+			{Name: "TestPanic"},
+		},
+	})
+
+	want := map[string][]string{
+		"p2": {"TestFail"},
+		"p3": {},
+		"p4": {"TestPanic"},
+		"p5": {"TestUntouched"},
+	}
+	if diff := cmp.Diff(want, lf.Packages); diff != "" {
+		t.Errorf("update() produced incorrect failures (-want, +got):\n%s", diff)
+	}
+}
+
+func TestExactTestsPattern(t *testing.T) {
+	want := `^(TestOne|TestTwo\.Three)$`
+	if got := exactTestsPattern([]string{"TestOne", "TestTwo.Three"}); got != want {
+		t.Errorf("exactTestsPattern() returned %q; want %q", got, want)
+	}
+}
+
+func TestFailedFlag(t *testing.T) {
+	cacheDir := t.TempDir()
+	commandtest.StubValue(t, &userCacheDir, func() (string, error) { return cacheDir, nil })
+
+	// Rerunning without any previous failures does nothing.
+	commandertest.ExecuteTest(t, &commandtest.ExecuteTestCase{
+		Node:       CLI().Node(),
+		Args:       []string{"--failed"},
+		WantStdout: "No failed tests from the previous run\n",
+		WantData: &command.Data{Values: map[string]interface{}{
+			pathArgs.Name():        []string{"."},
+			minCoverageFlag.Name(): 0.0,
+			failedFlag.Name():      true,
+		}},
+	})
+
+	if err := saveFailures(testModule(t), "", map[string]*packageResult{
+		"p1": {TestResult: testFailure},
+		"p2": {TestResult: buildFailure},
+		"p3": {TestResult: testSuccess},
+	}, map[string][]*testCaseResult{
+		"p1": {
+			{Name: "TestOne", Status: testFailed},
+			{Name: "TestTwo", Status: testPassed},
+			{Name: "TestThree", Status: testFailed},
+		},
+	}); err != nil {
+		t.Fatalf("saveFailures() returned error: %v", err)
+	}
+
+	jsonOut := filepath.Join(t.TempDir(), "report.json")
+	commandertest.ExecuteTest(t, &commandtest.ExecuteTestCase{
+		Node: CLI().Node(),
+		Args: []string{"--failed", "-t", "30", "-p", "3", "--json-out", jsonOut},
+		RunResponses: []*commandtest.FakeRun{
+			{Stdout: successEvents("p1", 0)},
+			{Stdout: failEvents("p2")},
+		},
+		WantStdout: stdoutLines(
+			successOutput("p1", 0),
+			"FAIL",
+			failLine("p2"),
+		),
+		WantErr:    fmt.Errorf("Tests failed for package: p2"),
+		WantStderr: "Tests failed for package: p2\n",
+		WantRunContents: []*commandtest.RunContents{
+			{Name: "go", Args: []string{"test", "-json", "-timeout", "30s", "p1", "-run", "^(TestOne|TestThree)$"}},
+			{Name: "go", Args: []string{"test", "-json", "-timeout", "30s", "p2"}},
+		},
+		WantData: &command.Data{Values: map[string]interface{}{
+			pathArgs.Name():         []string{"."},
+			minCoverageFlag.Name():  0.0,
+			timeoutFlag.Name():      30,
+			packageCountFlag.Name(): 3,
+			failedFlag.Name():       true,
+			jsonOutFlag.Name():      jsonOut,
+			"COVERAGE": map[string]*packageResult{
+				"p1": {
+					TestResult: testSuccess,
+					Line:       successOutput("p1", 0),
+				},
+				"p2": {
+					TestResult: testFailure,
+					Line:       failLine("p2"),
+				},
+			},
+		}},
+	})
+
+	b, err := os.ReadFile(jsonOut)
+	if err != nil {
+		t.Fatalf("failed to read JSON report: %v", err)
+	}
+	var report jsonReport
+	if err := json.Unmarshal(b, &report); err != nil {
+		t.Fatalf("failed to unmarshal JSON report: %v", err)
+	}
+	wantRuns := [][]string{
+		{"test", "-json", "-timeout", "30s", "p1", "-run", "^(TestOne|TestThree)$"},
+		{"test", "-json", "-timeout", "30s", "p2"},
+	}
+	if diff := cmp.Diff(wantRuns, report.GoTestRuns); diff != "" {
+		t.Errorf("gt --failed recorded incorrect go test runs (-want, +got):\n%s", diff)
+	}
+
+	lf, err := loadFailures(testModule(t), "")
+	if err != nil {
+		t.Fatalf("loadFailures() returned error: %v", err)
+	}
+	if diff := cmp.Diff(map[string][]string{"p2": {}}, lf.Packages); diff != "" {
+		t.Errorf("gt --failed saved incorrect failures (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,115 @@
+package gocli
+
+import (
+	"strings"
+
+	"github.com/leep-frog/command/command"
+)
+
+// testResultPrefixes are the prefixes of output lines that report the result
+// of a test (as opposed to output produced by the test itself).
+var testResultPrefixes = []string{
+	"--- PASS",
+	"--- FAIL",
+	"--- SKIP",
+}
+
+func isResultLine(line string) bool {
+	for _, prefix := range testResultPrefixes {
+		if strings.HasPrefix(strings.TrimSpace(line), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ownOutput returns the output lines produced by the test itself (excluding
+// framing and result lines).
+func ownOutput(tcr *testCaseResult) []string {
+	var lines []string
+	for _, line := range tcr.Output {
+		if !isFramingLine(line) && !isResultLine(line) {
+			lines = append(lines, line)
+		}
+	}
+	// Trim trailing empty lines
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// isUnsuccessful returns whether or not the test failed (or never completed
+// because of a panic or timeout).
+func isUnsuccessful(tcr *testCaseResult) bool {
+	return tcr.Status == testFailed || tcr.Status == testRunning
+}
+
+// failingTests returns the failing tests that should be included in the
+// failure summary. A failing test is included if it produced output itself or
+// if none of its subtests failed (i.e. it is the root cause of the failure).
+func failingTests(tests []*testCaseResult) []*testCaseResult {
+	var r []*testCaseResult
+	for _, tcr := range tests {
+		if !isUnsuccessful(tcr) {
+			continue
+		}
+		subFailures := failingTests(tcr.Subtests)
+		if len(subFailures) == 0 || len(ownOutput(tcr)) > 0 {
+			r = append(r, tcr)
+		}
+		r = append(r, subFailures...)
+	}
+	return r
+}
+
+// dedent removes the common leading whitespace from the provided lines.
+func dedent(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if n := len(line) - len(strings.TrimLeft(line, " \t")); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+
+	r := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if len(line) < indent {
+			r = append(r, strings.TrimSpace(line))
+		} else {
+			r = append(r, line[max(indent, 0):])
+		}
+	}
+	return r
+}
+
+// printFailureSummary prints the output of every failing test, grouped by
+// package.
+func printFailureSummary(o command.Output, packages []string, tests map[string][]*testCaseResult) {
+	var printedHeader bool
+	for _, p := range packages {
+		failures := failingTests(tests[p])
+		if len(failures) == 0 {
+			continue
+		}
+
+		if !printedHeader {
+			o.Stdoutln("Failure summary:")
+			printedHeader = true
+		}
+		o.Stdoutf("  %s\n", p)
+		for _, tcr := range failures {
+			o.Stdoutf("    %s\n", tcr.Name)
+			for _, line := range dedent(ownOutput(tcr)) {
+				if line == "" {
+					o.Stdoutln()
+				} else {
+					o.Stdoutf("      %s\n", line)
+				}
+			}
+		}
+	}
+}
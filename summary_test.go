@@ -0,0 +1,75 @@
+package gocli
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFailingTests(t *testing.T) {
+	leaf := &testCaseResult{
+		Name:   "TestA/leaf",
+		Status: testFailed,
+		Output: []string{"=== RUN   TestA/leaf", "    a_test.go:1: bad", "    --- FAIL: TestA/leaf (0.00s)"},
+	}
+	chatty := &testCaseResult{
+		Name:     "TestB",
+		Status:   testFailed,
+		Output:   []string{"=== RUN   TestB", "    b_test.go:1: also bad", "--- FAIL: TestB (0.00s)"},
+		Subtests: []*testCaseResult{{Name: "TestB/nested", Status: testFailed}},
+	}
+	panicked := &testCaseResult{
+		Name:   "TestC",
+		Status: testRunning,
+		Output: []string{"panic: oops"},
+	}
+
+	tests := []*testCaseResult{
+		{
+			Name:   "TestA",
+			Status: testFailed,
+			Output: []string{"=== RUN   TestA", "--- FAIL: TestA (0.00s)"},
+			Subtests: []*testCaseResult{
+				{Name: "TestA/passes", Status: testPassed, Output: []string{"    a_test.go:2: log"}},
+				leaf,
+			},
+		},
+		chatty,
+		{Name: "TestSkip", Status: testSkipped},
+		panicked,
+	}
+
+	want := []*testCaseResult{leaf, chatty, chatty.Subtests[0], panicked}
+	if diff := cmp.Diff(want, failingTests(tests)); diff != "" {
+		t.Errorf("failingTests() returned incorrect tests (-want, +got):\n%s", diff)
+	}
+}
+
+func TestDedent(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		lines []string
+		want  []string
+	}{
+		{
+			name: "handles no lines",
+			want: []string{},
+		},
+		{
+			name:  "removes common indentation",
+			lines: []string{"    a", "      b", "", "  ", "    c"},
+			want:  []string{"a", "  b", "", "", "c"},
+		},
+		{
+			name:  "handles no indentation",
+			lines: []string{"a", "  b"},
+			want:  []string{"a", "  b"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if diff := cmp.Diff(test.want, dedent(test.lines)); diff != "" {
+				t.Errorf("dedent(%v) returned incorrect lines (-want, +got):\n%s", test.lines, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package cover
+
+func Covered(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type thing struct{}
+
+func (t *thing) method() {
+	println("hi")
+}
@@ -0,0 +1,32 @@
+package nocover
+
+func Check(err error) error {
+	if err != nil { //gocli:nocover
+		return err
+	}
+	return nil
+}
+
+//gocli:nocover
+func Unreachable() {
+	panic("unreachable")
+}
+
+func Switch(i int) int {
+	switch i {
+	case 0:
+		return 0
+	//gocli:nocover
+	case 1:
+		return 1
+	}
+	return 2
+}
+
+func Trailing(b bool) int {
+	x := 1 //gocli:nocover
+	if b {
+		x++
+	}
+	return x
+}
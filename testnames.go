@@ -0,0 +1,122 @@
+package gocli
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// subtestNames returns the (mangled) names of the subtests in a test function.
+// Subtest names are found from string literals passed to `t.Run` and from
+// `name` fields of the elements of composite literals (i.e. test tables).
+func subtestNames(fd *ast.FuncDecl) []string {
+	var names []string
+	seen := map[string]bool{}
+	add := func(expr ast.Expr) {
+		lit, ok := expr.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return
+		}
+		s, err := strconv.Unquote(lit.Value)
+		if err != nil || s == "" {
+			return
+		}
+		if name := mangleSubtestName(s); !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			if sel, ok := n.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Run" && len(n.Args) == 2 {
+				add(n.Args[0])
+			}
+		case *ast.CompositeLit:
+			for _, elt := range n.Elts {
+				// Map tables have the test case as the value.
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					elt = kv.Value
+				}
+				if tc, ok := elt.(*ast.CompositeLit); ok {
+					add(tableName(tc))
+				}
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// tableName returns the value of the `name` field in a test table element
+// (or nil if there isn't one).
+func tableName(tc *ast.CompositeLit) ast.Expr {
+	for _, elt := range tc.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "name" {
+				return kv.Value
+			}
+		}
+	}
+	return nil
+}
+
+// mangleSubtestName rewrites a subtest name the same way the testing package
+// does (spaces are replaced with underscores and unprintable characters are
+// escaped).
+func mangleSubtestName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r):
+			b.WriteRune('_')
+		case !strconv.IsPrint(r):
+			q := strconv.QuoteRune(r)
+			b.WriteString(q[1 : len(q)-1])
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// funcFilterPattern returns the `-run` pattern for the provided function
+// filters. Top-level names are used as provided (e.g. `Execute` matches
+// `TestExecute`). Subtest names (e.g. `Execute/some case`) are matched
+// exactly at each level. Each filter is a separate alternative of the pattern
+// so subtests of one test aren't matched for another (`go test` splits the
+// pattern on top-level `|` before splitting each alternative on `/`).
+func funcFilterPattern(filters []string) string {
+	return testNamePattern(filters, "(%s)")
+}
+
+// skipPattern returns the `-skip` pattern for the provided function filters.
+// Unlike funcFilterPattern, top-level names are anchored (with an optional
+// `Test` prefix) so that `Slow` skips `TestSlow`, but not `TestNotSlow`.
+func skipPattern(filters []string) string {
+	return testNamePattern(filters, "^(Test)?(%s)$")
+}
+
+// testNamePattern returns a pattern with one alternative per filter, using
+// topFormat for the top-level name and exact matches for subtest names.
+func testNamePattern(filters []string, topFormat string) string {
+	var alts []string
+	seen := map[string]bool{}
+	for _, f := range filters {
+		parts := strings.Split(f, "/")
+		levels := []string{fmt.Sprintf(topFormat, parts[0])}
+		for _, part := range parts[1:] {
+			levels = append(levels, fmt.Sprintf("^(%s)$", regexp.QuoteMeta(mangleSubtestName(part))))
+		}
+		if alt := strings.Join(levels, "/"); !seen[alt] {
+			seen[alt] = true
+			alts = append(alts, alt)
+		}
+	}
+	return strings.Join(alts, "|")
+}
@@ -0,0 +1,81 @@
+package gocli
+
+import (
+	"testing"
+)
+
+func TestMangleSubtestName(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		want string
+	}{
+		{name: "simple", want: "simple"},
+		{name: "with spaces\tand tabs", want: "with_spaces_and_tabs"},
+		{name: "bell\a", want: `bell\a`},
+		{name: "ünïcode", want: "ünïcode"},
+	} {
+		if got := mangleSubtestName(test.name); got != test.want {
+			t.Errorf("mangleSubtestName(%q) returned %q; want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFuncFilterPattern(t *testing.T) {
+	for _, test := range []struct {
+		filters []string
+		want    string
+	}{
+		{
+			filters: []string{"One"},
+			want:    "(One)",
+		},
+		{
+			filters: []string{"One", "Two", "One"},
+			want:    "(One)|(Two)",
+		},
+		{
+			filters: []string{"One/some case"},
+			want:    "(One)/^(some_case)$",
+		},
+		{
+			filters: []string{"One/a", "One/b+c", "Two/a/deep (1)"},
+			want:    `(One)/^(a)$|(One)/^(b\+c)$|(Two)/^(a)$/^(deep_\(1\))$`,
+		},
+		{
+			filters: []string{"A/x", "B/y"},
+			want:    "(A)/^(x)$|(B)/^(y)$",
+		},
+		{
+			filters: []string{"One/a", "Two"},
+			want:    "(One)/^(a)$|(Two)",
+		},
+		{
+			filters: []string{"One|Uno"},
+			want:    "(One|Uno)",
+		},
+	} {
+		if got := funcFilterPattern(test.filters); got != test.want {
+			t.Errorf("funcFilterPattern(%v) returned %q; want %q", test.filters, got, test.want)
+		}
+	}
+}
+
+func TestSkipPattern(t *testing.T) {
+	for _, test := range []struct {
+		filters []string
+		want    string
+	}{
+		{
+			filters: []string{"Slow"},
+			want:    "^(Test)?(Slow)$",
+		},
+		{
+			filters: []string{"Add/bad case", "B"},
+			want:    "^(Test)?(Add)$/^(bad_case)$|^(Test)?(B)$",
+		},
+	} {
+		if got := skipPattern(test.filters); got != test.want {
+			t.Errorf("skipPattern(%v) returned %q; want %q", test.filters, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package gocli
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/leep-frog/command/command"
+)
+
+// coverageRule is a minimum coverage threshold for the packages that match a
+// package pattern.
+type coverageRule struct {
+	// Spec is the rule as provided by the user (e.g. `./internal/...=80`).
+	Spec string
+	// Pattern is the import path pattern that the rule applies to.
+	Pattern string
+	// MinCoverage is the minimum coverage percentage.
+	MinCoverage float64
+}
+
+// parseCoverageRules parses `pattern=percent` rules. Relative patterns (e.g.
+// `./cmd/...`) are resolved relative to the provided directory, which must be
+// inside of the provided module.
+func parseCoverageRules(specs []string, mod *goModule, dir string) ([]*coverageRule, error) {
+	var rules []*coverageRule
+	for _, spec := range specs {
+		pattern, percent, ok := strings.Cut(spec, "=")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid coverage rule %q: expected format PATTERN=PERCENT", spec)
+		}
+		mc, err := strconv.ParseFloat(percent, 64)
+		if err != nil || mc < 0 || mc > 100 {
+			return nil, fmt.Errorf("invalid coverage rule %q: percent must be a number between 0 and 100", spec)
+		}
+
+		if isRelativePattern(pattern) {
+			if mod == nil {
+				return nil, fmt.Errorf("invalid coverage rule %q: relative patterns can only be used inside of a go module", spec)
+			}
+			rel, err := filepath.Rel(mod.Dir, filepath.Join(dir, filepath.FromSlash(pattern)))
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return nil, fmt.Errorf("invalid coverage rule %q: pattern is outside of module %s", spec, mod.Path)
+			}
+			pattern = path.Join(mod.Path, filepath.ToSlash(rel))
+		}
+		rules = append(rules, &coverageRule{spec, pattern, mc})
+	}
+	return rules, nil
+}
+
+// match returns whether or not the rule applies to the provided package, and
+// if so, how specific the match is (higher values are more specific).
+func (cr *coverageRule) match(pkg string) (int, bool) {
+	if cr.Pattern == "..." {
+		return 0, true
+	}
+	if prefix, ok := strings.CutSuffix(cr.Pattern, "/..."); ok {
+		if pkg == prefix || strings.HasPrefix(pkg, prefix+"/") {
+			return 2 * len(prefix), true
+		}
+		return 0, false
+	}
+	// Exact matches beat wildcard matches with the same prefix.
+	return 2*len(cr.Pattern) + 1, pkg == cr.Pattern
+}
+
+// coverageRuleFor returns the most specific rule that applies to the provided
+// package (or nil if no rule applies). If multiple rules are equally specific,
+// the last one wins.
+func coverageRuleFor(rules []*coverageRule, pkg string) *coverageRule {
+	var best *coverageRule
+	bestScore := -1
+	for _, cr := range rules {
+		if score, ok := cr.match(pkg); ok && score >= bestScore {
+			best, bestScore = cr, score
+		}
+	}
+	return best
+}
+
+// checkPackageCoverage returns an error if the coverage of a package is less
+// than the minimum coverage from the most specific matching rule (or mc if no
+// rule matches). The applied rule is recorded in pr.
+func checkPackageCoverage(o command.Output, p string, pr *packageResult, report *coverageReport, rules []*coverageRule, mc float64) error {
+	// Packages without any statements can't be covered.
+	if pc := report.packageCoverage(p); pc == nil || pc.Counts.Statements == 0 {
+		return nil
+	}
+	if cr := coverageRuleFor(rules, p); cr != nil {
+		pr.CoverageRule = cr.Spec
+		if pr.Coverage < cr.MinCoverage {
+			return o.Stderrf("Coverage of package %q (%s) must be at least %s (rule: %s)\n", p, percentFormat(pr.Coverage), percentFormat(cr.MinCoverage), cr.Spec)
+		}
+		return nil
+	}
+	if pr.Coverage < mc {
+		return o.Stderrf("Coverage of package %q (%s) must be at least %s\n", p, percentFormat(pr.Coverage), percentFormat(mc))
+	}
+	return nil
+}
+
+// coveragePolicy is the set of coverage checks requested by the coverage
+// flags (shared by the test and merge commands).
+type coveragePolicy struct {
+	minCoverage     float64
+	rules           []*coverageRule
+	excludes        []string
+	diffBase        bool
+	requireExported bool
+}
+
+// newCoveragePolicy parses and validates the coverage flags. Relative rule
+// patterns are resolved relative to the provided directory.
+func newCoveragePolicy(o command.Output, d *command.Data, mod *goModule, dir string) (*coveragePolicy, error) {
+	rules, err := parseCoverageRules(coverageRuleFlag.Get(d), mod, dir)
+	if err != nil {
+		return nil, o.Err(err)
+	}
+	if diffBaseFlag.Provided(d) && len(rules) > 0 {
+		return nil, o.Stderrln("Cannot set diff-base and package-coverage flags simultaneously")
+	}
+	if err := validateExcludePatterns(excludeFlag.Get(d)); err != nil {
+		return nil, o.Err(err)
+	}
+	return &coveragePolicy{
+		minCoverage:     minCoverageFlag.Get(d),
+		rules:           rules,
+		excludes:        excludeFlag.Get(d),
+		diffBase:        diffBaseFlag.Provided(d),
+		requireExported: requireExpFlag.Get(d),
+	}, nil
+}
+
+// hasThresholds returns whether any of the coverage checks are enabled.
+func (cp *coveragePolicy) hasThresholds() bool {
+	return cp.minCoverage > 0 || len(cp.rules) > 0 || cp.diffBase || cp.requireExported
+}
+
+// report computes the coverage report for the profile (without the excluded
+// code).
+func (cp *coveragePolicy) report(profile *coverProfile, mod *goModule) *coverageReport {
+	profile, excluded := excludeCoverage(profile, mod, cp.excludes)
+	report := computeCoverage(profile, mod)
+	report.Excluded = excluded
+	return report
+}
+
+// check returns an error if any of the packages failed or didn't meet the
+// coverage thresholds (diff coverage, if provided, replaces the per-package
+// thresholds). The report may be nil if coverage wasn't measured.
+func (cp *coveragePolicy) check(o command.Output, packages []string, results map[string]*packageResult, report *coverageReport, dc *diffCoverage, mod *goModule) error {
+	var retErr error
+	for _, p := range packages {
+		pr := results[p]
+		switch pr.TestResult {
+		case noTestFiles:
+		case testSuccess:
+			if dc != nil {
+				continue
+			}
+			if err := checkPackageCoverage(o, p, pr, report, cp.rules, cp.minCoverage); err != nil {
+				retErr = err
+			}
+		default:
+			retErr = o.Stderrln(pr.failureMessage(p))
+		}
+	}
+
+	if dc != nil {
+		if err := checkDiffCoverage(o, dc, cp.minCoverage); err != nil {
+			retErr = err
+		}
+	}
+	if report != nil && cp.requireExported {
+		if err := checkExportedFuncs(o, funcListings(report, mod)); err != nil {
+			retErr = err
+		}
+	}
+	return retErr
+}
@@ -0,0 +1,113 @@
+package gocli
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseCoverageRules(t *testing.T) {
+	mod := &goModule{"example.com/mod", filepath.FromSlash("/src/mod")}
+
+	for _, test := range []struct {
+		name    string
+		specs   []string
+		mod     *goModule
+		dir     string
+		want    []*coverageRule
+		wantErr error
+	}{
+		{
+			name: "handles no rules",
+		},
+		{
+			name:  "parses rules",
+			specs: []string{"./...=50", "./internal/...=80", "../cmd=0", "example.com/other/...=12.5", ".=100"},
+			mod:   mod,
+			dir:   filepath.Join(mod.Dir, "pkg"),
+			want: []*coverageRule{
+				{"./...=50", "example.com/mod/pkg/...", 50},
+				{"./internal/...=80", "example.com/mod/pkg/internal/...", 80},
+				{"../cmd=0", "example.com/mod/cmd", 0},
+				{"example.com/other/...=12.5", "example.com/other/...", 12.5},
+				{".=100", "example.com/mod/pkg", 100},
+			},
+		},
+		{
+			name:    "fails if no percent",
+			specs:   []string{"./...=50", "."},
+			mod:     mod,
+			dir:     mod.Dir,
+			wantErr: fmt.Errorf(`invalid coverage rule ".": expected format PATTERN=PERCENT`),
+		},
+		{
+			name:    "fails if invalid percent",
+			specs:   []string{"./...=abc"},
+			mod:     mod,
+			dir:     mod.Dir,
+			wantErr: fmt.Errorf(`invalid coverage rule "./...=abc": percent must be a number between 0 and 100`),
+		},
+		{
+			name:    "fails if percent too large",
+			specs:   []string{"./...=100.1"},
+			mod:     mod,
+			dir:     mod.Dir,
+			wantErr: fmt.Errorf(`invalid coverage rule "./...=100.1": percent must be a number between 0 and 100`),
+		},
+		{
+			name:    "fails if relative pattern without module",
+			specs:   []string{"./...=50"},
+			dir:     mod.Dir,
+			wantErr: fmt.Errorf(`invalid coverage rule "./...=50": relative patterns can only be used inside of a go module`),
+		},
+		{
+			name:    "fails if relative pattern outside of module",
+			specs:   []string{"../...=50"},
+			mod:     mod,
+			dir:     mod.Dir,
+			wantErr: fmt.Errorf(`invalid coverage rule "../...=50": pattern is outside of module example.com/mod`),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseCoverageRules(test.specs, test.mod, test.dir)
+			if diff := cmp.Diff(test.wantErr, err, cmpErrors()); diff != "" {
+				t.Errorf("parseCoverageRules(%v) returned incorrect error (-want, +got):\n%s", test.specs, diff)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("parseCoverageRules(%v) returned incorrect rules (-want, +got):\n%s", test.specs, diff)
+			}
+		})
+	}
+}
+
+func TestCoverageRuleFor(t *testing.T) {
+	all := &coverageRule{"...=10", "...", 10}
+	mod := &coverageRule{"m/...=20", "m/...", 20}
+	internal := &coverageRule{"m/internal/...=30", "m/internal/...", 30}
+	exact := &coverageRule{"m/internal=40", "m/internal", 40}
+	override := &coverageRule{"m/internal=50", "m/internal", 50}
+	rules := []*coverageRule{internal, exact, all, mod, override}
+
+	for _, test := range []struct {
+		pkg  string
+		want *coverageRule
+	}{
+		{pkg: "other", want: all},
+		{pkg: "m", want: mod},
+		{pkg: "mod", want: all},
+		{pkg: "m/cmd", want: mod},
+		{pkg: "m/internal", want: override},
+		{pkg: "m/internal/x", want: internal},
+		{pkg: "m/internalx", want: mod},
+	} {
+		if got := coverageRuleFor(rules, test.pkg); got != test.want {
+			t.Errorf("coverageRuleFor(%q) returned %v; want %v", test.pkg, got, test.want)
+		}
+	}
+
+	if got := coverageRuleFor([]*coverageRule{internal}, "m/cmd"); got != nil {
+		t.Errorf("coverageRuleFor(%q) returned %v; want nil", "m/cmd", got)
+	}
+}
@@ -0,0 +1,109 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/leep-frog/command/command"
+	"golang.org/x/exp/slices"
+)
+
+// lineRange is an inclusive range of lines.
+type lineRange struct {
+	Start int
+	End   int
+}
+
+func (lr lineRange) String() string {
+	if lr.Start == lr.End {
+		return fmt.Sprintf("%d", lr.Start)
+	}
+	return fmt.Sprintf("%d-%d", lr.Start, lr.End)
+}
+
+// uncoveredFile contains the uncovered lines of a single file.
+type uncoveredFile struct {
+	// Name is the display name of the file.
+	Name string
+	// Path is the local path to the file (or empty if it could not be found).
+	Path string
+	// Statements is the number of uncovered statements in the file.
+	Statements int
+	Ranges     []lineRange
+}
+
+// uncoveredRanges returns the merged line ranges of the blocks that were never
+// run (blocks are expected to be sorted).
+func uncoveredRanges(blocks []*coverBlock) []lineRange {
+	var r []lineRange
+	for _, b := range blocks {
+		if b.Count > 0 || b.NumStmt == 0 {
+			continue
+		}
+		if n := len(r); n > 0 && b.StartLine <= r[n-1].End+1 {
+			r[n-1].End = max(r[n-1].End, b.EndLine)
+			continue
+		}
+		r = append(r, lineRange{b.StartLine, b.EndLine})
+	}
+	return r
+}
+
+// uncoveredFiles returns every file with uncovered statements, sorted by the
+// number of uncovered statements (most uncovered first).
+func uncoveredFiles(report *coverageReport, mod *goModule) []*uncoveredFile {
+	var r []*uncoveredFile
+	for _, pc := range report.Packages {
+		for _, fc := range pc.Files {
+			missed := fc.Counts.Statements - fc.Counts.Covered
+			if missed == 0 {
+				continue
+			}
+			r = append(r, &uncoveredFile{
+				Name:       mod.relativePath(fc.Name),
+				Path:       fc.Path,
+				Statements: missed,
+				Ranges:     uncoveredRanges(fc.Blocks),
+			})
+		}
+	}
+	slices.SortFunc(r, func(a, b *uncoveredFile) int {
+		if a.Statements != b.Statements {
+			return b.Statements - a.Statements
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return r
+}
+
+// printUncovered prints the uncovered line ranges of every file (and
+// optionally, the source code of each range).
+func printUncovered(o command.Output, files []*uncoveredFile, snippets bool) {
+	if len(files) == 0 {
+		return
+	}
+
+	o.Stdoutln("Uncovered lines:")
+	for _, uf := range files {
+		o.Stdoutf("  %s (%d uncovered statements)\n", uf.Name, uf.Statements)
+
+		var source []string
+		if snippets && uf.Path != "" {
+			// Snippets are best effort, so just print the ranges if the file can't be read.
+			if b, err := os.ReadFile(uf.Path); err == nil {
+				source = strings.Split(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n")
+			}
+		}
+
+		for _, lr := range uf.Ranges {
+			o.Stdoutf("    %s\n", lr)
+			if source == nil {
+				continue
+			}
+			for line := lr.Start; line <= lr.End && line <= len(source); line++ {
+				o.Stdoutf("      %4d | %s\n", line, source[line-1])
+			}
+		}
+	}
+}
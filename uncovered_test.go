@@ -0,0 +1,91 @@
+package gocli
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/leep-frog/command/commandtest"
+)
+
+func TestUncoveredRanges(t *testing.T) {
+	blocks := []*coverBlock{
+		{StartLine: 1, EndLine: 2, NumStmt: 1, Count: 0},
+		{StartLine: 2, EndLine: 4, NumStmt: 1, Count: 0},
+		{StartLine: 5, EndLine: 5, NumStmt: 1, Count: 0},
+		{StartLine: 6, EndLine: 8, NumStmt: 2, Count: 1},
+		{StartLine: 9, EndLine: 9, NumStmt: 0, Count: 0},
+		{StartLine: 10, EndLine: 10, NumStmt: 1, Count: 0},
+		{StartLine: 10, EndLine: 12, NumStmt: 1, Count: 0},
+	}
+	want := []lineRange{{1, 5}, {10, 12}}
+	if diff := cmp.Diff(want, uncoveredRanges(blocks)); diff != "" {
+		t.Errorf("uncoveredRanges() returned incorrect ranges (-want, +got):\n%s", diff)
+	}
+}
+
+func TestPrintUncovered(t *testing.T) {
+	mod := testModule(t)
+	cp := &coverProfile{
+		Mode: "set",
+		Files: map[string][]*coverBlock{
+			testCoverFile: {
+				{StartLine: 3, StartCol: 26, EndLine: 4, EndCol: 7, NumStmt: 1, Count: 1},
+				{StartLine: 4, StartCol: 7, EndLine: 6, EndCol: 3, NumStmt: 1, Count: 0},
+				{StartLine: 7, StartCol: 2, EndLine: 7, EndCol: 10, NumStmt: 1, Count: 1},
+				{StartLine: 12, StartCol: 26, EndLine: 14, EndCol: 2, NumStmt: 1, Count: 0},
+			},
+			testModulePath + "/p1/file.go": {
+				{StartLine: 1, StartCol: 1, EndLine: 1, EndCol: 10, NumStmt: 3, Count: 0},
+			},
+			testModulePath + "/p2/file.go": {
+				{StartLine: 1, StartCol: 1, EndLine: 1, EndCol: 10, NumStmt: 3, Count: 1},
+			},
+		},
+	}
+	files := uncoveredFiles(computeCoverage(cp, mod), mod)
+
+	for _, test := range []struct {
+		name     string
+		snippets bool
+		want     []string
+	}{
+		{
+			name: "prints uncovered ranges",
+			want: []string{
+				"Uncovered lines:",
+				"  p1/file.go (3 uncovered statements)",
+				"    1",
+				"  testdata/cover/cover.go (2 uncovered statements)",
+				"    4-6",
+				"    12-14",
+			},
+		},
+		{
+			name:     "prints uncovered ranges with snippets",
+			snippets: true,
+			want: []string{
+				"Uncovered lines:",
+				"  p1/file.go (3 uncovered statements)",
+				"    1",
+				"  testdata/cover/cover.go (2 uncovered statements)",
+				"    4-6",
+				"         4 | \tif b {",
+				"         5 | \t\treturn 1",
+				"         6 | \t}",
+				"    12-14",
+				"        12 | func (t *thing) method() {",
+				"        13 | \tprintln(\"hi\")",
+				"        14 | }",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			o := commandtest.NewOutput()
+			printUncovered(o, files, test.snippets)
+			o.Close()
+			if diff := cmp.Diff(stdoutLines(test.want...), o.GetStdout()); diff != "" {
+				t.Errorf("printUncovered() printed incorrect output (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}